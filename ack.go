@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// Input acknowledgment for client-side prediction.
+//
+// A client may stamp each move with an increasing "inputSeq". Once the server
+// has applied a move, it sends that player alone an "ack" message carrying
+// its latest applied inputSeq in "acks", keyed by side. This is separate from
+// "seq", the server's event sequence. The ack follows the update showing the
+// move, so the paddle position already received includes every move up to
+// the acknowledged inputSeq, and a predicting client:
+//
+//   - drops the inputs it kept whose inputSeq is at or below the ack,
+//   - takes the server's position for its paddle,
+//   - replays the inputs still unacknowledged on top of it.
+//
+// Moves the server drops (throttled, or for another paddle) are never acked
+// themselves, but a later ack covers them, so clients should treat acks as
+// cumulative. An inputSeq lower than the last acked one is applied but
+// doesn't move the ack backwards. Acks start over when the player's
+// connection closes. pong.v1 clients don't get acks.
+
+// ackMove records that a player's move with the given inputSeq was applied.
+// Caller must hold room lock.
+func (room *Room) ackMove(player string, seq uint64) {
+	if seq == 0 || seq <= room.Acks[player] {
+		return
+	}
+	if room.Acks == nil {
+		room.Acks = make(map[string]uint64)
+	}
+	room.Acks[player] = seq
+}
+
+// clearAck forgets a departed player's acknowledged inputSeq. Caller must hold
+// room lock.
+func (room *Room) clearAck(player string) {
+	delete(room.Acks, player)
+	delete(room.sentAcks, player)
+}
+
+// sendAcks tells each player about moves applied since their last ack, on
+// their own connection only. Caller must hold room lock and clientsMutex.
+func (room *Room) sendAcks() {
+	for conn, player := range room.clients {
+		seq, ok := room.Acks[player]
+		if !ok || seq == room.sentAcks[player] {
+			continue
+		}
+		if room.sentAcks == nil {
+			room.sentAcks = make(map[string]uint64)
+		}
+		room.sentAcks[player] = seq
+		if room.legacy(conn) {
+			continue
+		}
+		frame, err := json.Marshal(Message{Type: AckMsg, Acks: map[string]uint64{player: seq}})
+		if err != nil {
+			slog.Error("Error marshaling message", "room", room.ID, "msg_type", AckMsg, "err", err)
+			continue
+		}
+		if err := room.sendTo(conn, websocket.TextMessage, frame); err != nil {
+			room.dropClient(conn, err, "sending ack")
+		}
+	}
+}
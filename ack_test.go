@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAckMove(t *testing.T) {
+	room := NewServer(testConfig(t), nil).NewSteppedRoom("acks", RoomOptions{Mode: ClassicMode, Balls: 1})
+	for _, step := range []struct {
+		seq  uint64
+		want uint64
+	}{
+		{seq: 0, want: 0}, // Unstamped moves aren't acked
+		{seq: 3, want: 3},
+		{seq: 7, want: 7},
+		{seq: 5, want: 7}, // Never moves backwards
+	} {
+		room.ackMove("left", step.seq)
+		if got := room.Acks["left"]; got != step.want {
+			t.Fatalf("after move %d ack is %d, want %d", step.seq, got, step.want)
+		}
+	}
+	room.clearAck("left")
+	if _, ok := room.Acks["left"]; ok {
+		t.Fatal("ack kept after clearAck")
+	}
+}
+
+func TestMoveAckedByInputSeq(t *testing.T) {
+	cfg := testConfig(t)
+	_, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	// Close enough to the start that the jump limit doesn't cut it short
+	y := cfg.MaxPaddleY()/2 - 50
+	if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &y, InputSeq: 9}); err != nil {
+		t.Fatal(err)
+	}
+	// The ack comes after the frame showing the move
+	shown := 0
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case UpdateMessage, DeltaMsg:
+			if msg.LeftY != 0 {
+				shown = msg.LeftY
+			}
+			if msg.Acks != nil {
+				t.Fatalf("%s carries acks %v", msg.Type, msg.Acks)
+			}
+		case AckMsg:
+			if msg.Acks["left"] != 9 || shown != y {
+				t.Fatalf("ack %v after the paddle was shown at %d, want move 9 to %d", msg.Acks, shown, y)
+			}
+			if msg.InputSeq != 0 {
+				t.Fatalf("ack carries inputSeq %d", msg.InputSeq)
+			}
+			return
+		}
+	}
+}
+
+func TestAckSentOnlyToMover(t *testing.T) {
+	cfg := testConfig(t)
+	_, ts := newTestServer(t, cfg)
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+
+	y := cfg.MaxPaddleY()/2 - 50
+	if err := left.WriteJSON(Message{Type: MoveMessage, Y: &y, InputSeq: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if ack := readUntil(t, left, AckMsg); ack.Acks["left"] != 4 {
+		t.Fatalf("left got ack %v, want move 4", ack.Acks)
+	}
+
+	// The ack was queued for left right after the frame showing the move, so
+	// a few frames past that one the opponent would have had it too
+	after := -1
+	for after < 5 {
+		msg, err := readMessage(right)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == AckMsg || msg.Acks != nil {
+			t.Fatalf("opponent got %s with acks %v", msg.Type, msg.Acks)
+		}
+		if after >= 0 {
+			after++
+		} else if msg.LeftY == y && (msg.Type == UpdateMessage || msg.Type == DeltaMsg) {
+			after = 0
+		}
+	}
+}
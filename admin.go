@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Environment variable holding the shared secret for /admin endpoints. Unset,
+// every admin request is refused.
+const adminTokenEnv = "PONG_ADMIN_TOKEN"
+
+// bearerTokenMatches reports whether a request carries token as its bearer
+// token. An empty token matches nothing.
+func bearerTokenMatches(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// adminRoom checks an admin request and returns the room it names, writing
+// the error response and returning nil if it can't go ahead
+func (s *Server) adminRoom(w http.ResponseWriter, r *http.Request) *Room {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+	if !bearerTokenMatches(r, os.Getenv(adminTokenEnv)) {
+		slog.Warn("Refused admin request", "path", r.URL.Path, "ip", clientIP(r))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	room := s.lookupRoom(r.URL.Query().Get("room"))
+	if room == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return nil
+	}
+	return room
+}
+
+// handleAdminReset puts a room's ball back in the middle and serves it
+// again, keeping the score
+func (s *Server) handleAdminReset(w http.ResponseWriter, r *http.Request) {
+	room := s.adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	slog.Info("Admin reset room", "room", room.ID, "ip", clientIP(r))
+
+	room.Lock()
+	room.restartRally()
+	room.Unlock()
+	room.broadcastGameState()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminKick disconnects the player on a paddle and frees it
+func (s *Server) handleAdminKick(w http.ResponseWriter, r *http.Request) {
+	room := s.adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	player := r.URL.Query().Get("player")
+	slog.Info("Admin kick", "room", room.ID, "player", player, "ip", clientIP(r))
+
+	if !room.kick(player) {
+		http.Error(w, "nobody holds that paddle", http.StatusNotFound)
+		return
+	}
+	room.promoteFromQueue()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// kick frees a paddle and closes the connection holding it, reporting false
+// if nobody holds it. The paddle isn't held for a reconnect: its token is
+// revoked and the connection's own cleanup finds it already freed.
+func (room *Room) kick(player string) bool {
+	room.Lock()
+	defer room.Unlock()
+
+	room.assignMutex.Lock()
+	var conn *websocket.Conn
+	for c, side := range room.assignedPlayers {
+		if side == player {
+			conn = c
+		}
+	}
+	if conn != nil {
+		room.unassign(conn)
+		delete(room.tokens, player)
+	}
+	room.assignMutex.Unlock()
+	if conn == nil {
+		return false
+	}
+	room.forgetPlayer(player)
+
+	room.closeConn(conn, websocket.ClosePolicyViolation, "removed by an administrator")
+	slog.Info("Player kicked", "room", room.ID, "remote_addr", conn.RemoteAddr().String(), "player", player)
+	return true
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"math"
+)
+
+// Max pixels per tick the track and block AIs move a paddle
+const aiPaddleSpeed = 5
+
+// How close (horizontally) the ball must be before the block AI reacts
+const blockReactDistance = 150
+
+// AIPolicy picks where the paddle on the given side heads. Caller must hold
+// room lock.
+type AIPolicy func(room *Room, side string) int
+
+// AILevel is how an AI plays a paddle: where it heads, and how well it keeps
+// up with the ball
+type AILevel struct {
+	Policy   AIPolicy
+	Reaction float64 // Fraction of the distance to its target closed each tick
+	MaxSpeed int     // Max pixels per tick the paddle moves
+	Error    int     // Most pixels it misjudges its target by, rolled again on every hit
+}
+
+// AI levels by name, for -ai-difficulty and the solo opponent a lone player
+// asks for with /ws?ai=<level>
+var aiLevels = map[string]AILevel{
+	"easy":   {Policy: (*Room).trackBall, Reaction: 0.05, MaxSpeed: 3, Error: 30},
+	"medium": {Policy: (*Room).trackBall, Reaction: 0.1, MaxSpeed: 5, Error: 15},
+	"hard":   {Policy: (*Room).trackBall, Reaction: 0.2, MaxSpeed: 8},
+	"track":  {Policy: (*Room).trackBall, Reaction: 1, MaxSpeed: aiPaddleSpeed},
+	"block":  {Policy: (*Room).blockBall, Reaction: 1, MaxSpeed: aiPaddleSpeed},
+}
+
+// Level used whenever the server drives a backgrounded player's paddle
+var aiDifficulty = flag.String("ai-difficulty", "track", `AI level for backgrounded paddles: "track" follows the ball, "block" holds the center and only reacts to a close ball; "easy", "medium" and "hard" also work`)
+
+// aiAim is how far off its target an AI paddle aims, and the rally hit it was
+// rolled on
+type aiAim struct {
+	hits  int
+	error int
+}
+
+// driveAI moves a side's paddle one tick the way an AI of the given level
+// plays it. Caller must hold room lock.
+func (room *Room) driveAI(side string, level AILevel) {
+	y := &room.PanYLeft
+	if side == "right" {
+		y = &room.PanYRight
+	}
+	target := level.Policy(room, side) + room.aimError(side, level)
+	step := int(math.Round(float64(target-*y) * level.Reaction))
+	step = max(-level.MaxSpeed, min(step, level.MaxSpeed))
+	*y = room.clampToReach(side, room.Config.clampYPosition(*y+step))
+}
+
+// aimError is how far off its target a level's AI aims on the side's current
+// return. It is rolled again whenever a paddle hits the ball. Caller must
+// hold room lock.
+func (room *Room) aimError(side string, level AILevel) int {
+	if level.Error <= 0 {
+		return 0
+	}
+	aim, ok := room.aiAims[side]
+	if !ok || aim.hits != room.RallyHits {
+		aim = aiAim{hits: room.RallyHits, error: room.rand.Intn(2*level.Error+1) - level.Error}
+		if room.aiAims == nil {
+			room.aiAims = make(map[string]aiAim)
+		}
+		room.aiAims[side] = aim
+	}
+	return aim.error
+}
+
+// driveAIPaddle moves a backgrounded player's paddle at the configured level.
+// Caller must hold room lock.
+func (room *Room) driveAIPaddle(side string) {
+	room.driveAI(side, aiLevels[*aiDifficulty])
+}
+
+// driveSoloOpponent plays the empty paddle while a lone player has asked for
+// an AI opponent. Once a second player takes the paddle it is theirs again.
+// Caller must hold room lock.
+func (room *Room) driveSoloOpponent() {
+	level, ok := aiLevels[room.soloAI]
+	if !ok {
+		return
+	}
+	side := room.emptySide()
+	if side == "" {
+		return
+	}
+
+	// Don't leave the player waiting on an aimed serve
+	if room.Serving == side {
+		room.launchServe(side)
+	}
+	room.driveAI(side, level)
+}
+
+// emptySide returns the free paddle when exactly one is assigned, otherwise ""
+func (room *Room) emptySide() string {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+
+	if len(room.assignedPlayers) != 1 {
+		return ""
+	}
+	for _, player := range room.assignedPlayers {
+		return opponent(player)
+	}
+	return ""
+}
+
+// trackBall heads for the incoming ball's Y. Caller must hold room lock.
+func (room *Room) trackBall(side string) int {
+	return int(room.incomingBall(side).Y) - room.Config.PaddleHeight/2
+}
+
+// blockBall stays near the center and only moves to block once the ball is
+// within blockReactDistance of its paddle. Caller must hold room lock.
+func (room *Room) blockBall(side string) int {
+	paddleX := float64(room.Config.PaddleWidth)
+	if side == "right" {
+		paddleX = float64(room.Config.Width - room.Config.PaddleWidth)
+	}
+	if math.Abs(room.incomingBall(side).X-paddleX) > blockReactDistance {
+		return room.Config.MaxPaddleY() / 2
+	}
+	return room.trackBall(side)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBlockAIMovesOnlyForCloseBall(t *testing.T) {
+	setFlag(t, aiDifficulty, "block")
+	room := newSteppedTestRoom(t)
+	cfg := room.Config
+	center := cfg.MaxPaddleY() / 2
+	rightX := float64(cfg.Width - cfg.PaddleWidth)
+	tests := []struct {
+		name  string
+		side  string
+		ballX float64
+		moves bool
+	}{
+		{"far from left", "left", float64(cfg.PaddleWidth) + blockReactDistance + 1, false},
+		{"close to left", "left", float64(cfg.PaddleWidth) + blockReactDistance - 1, true},
+		{"far from right", "right", rightX - blockReactDistance - 1, false},
+		{"close to right", "right", rightX - blockReactDistance + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room.Lock()
+			defer room.Unlock()
+			room.PanYLeft, room.PanYRight = center, center
+			vx := -5.0
+			if tt.side == "right" {
+				vx = 5
+			}
+			// Well off the paddle, so tracking it would move it
+			room.Ball = Ball{X: tt.ballX, Y: float64(cfg.Height) - BallRadius, Vx: vx}
+			room.driveAIPaddle(tt.side)
+			y := room.PanYLeft
+			if tt.side == "right" {
+				y = room.PanYRight
+			}
+			if moved := y != center; moved != tt.moves {
+				t.Errorf("paddle went from %d to %d with the ball at x=%v, want moved=%v", center, y, tt.ballX, tt.moves)
+			}
+		})
+	}
+}
+
+func TestBlockAIReturnsToCenter(t *testing.T) {
+	setFlag(t, aiDifficulty, "block")
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	room.PanYLeft = 0
+	room.Ball = Ball{X: float64(room.Config.Width) / 2, Y: float64(room.Config.Height) / 2, Vx: -5}
+	for range room.Config.MaxPaddleY() {
+		room.driveAIPaddle("left")
+	}
+	if room.PanYLeft != room.Config.MaxPaddleY()/2 {
+		t.Fatalf("block AI settled at %d with the ball far away, want the center %d", room.PanYLeft, room.Config.MaxPaddleY()/2)
+	}
+}
+
+func TestSoloBlockOpponentReturnsBall(t *testing.T) {
+	setFlag(t, winningScore, 100)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	s, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "?ai=block")
+	if msg := readUntil(t, conn, AssignMessage); msg.Player != "left" {
+		t.Fatalf("solo player assigned %q, want left", msg.Player)
+	}
+	// The player stays out of the way, so only the AI returns the ball
+	go dodge(conn, cfg, GameOverMsg)
+
+	room := s.lookupRoom(DefaultRoomID)
+	waitFor(t, "the block AI to return the ball", func() bool {
+		room.Lock()
+		defer room.Unlock()
+		return room.lastHitter == "right"
+	})
+}
+
+func TestUnknownAILevelRejected(t *testing.T) {
+	_, ts := newTestServer(t, testConfig(t))
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(ts, "?ai=clairvoyant"), nil)
+	if err == nil {
+		t.Fatal("joined asking for an unknown AI level")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown AI level got %v, want a 400", resp)
+	}
+}
+
+func TestAILevelsStepAtTheirOwnPace(t *testing.T) {
+	for name, level := range aiLevels {
+		t.Run(name, func(t *testing.T) {
+			room := newSteppedTestRoom(t)
+			room.Lock()
+			defer room.Unlock()
+			room.PanYLeft = 0
+			// Far below, so every level is limited by its speed or reaction
+			room.Ball = Ball{X: float64(room.Config.Width) / 2, Y: float64(room.Config.Height) - BallRadius, Vx: -5}
+			target := level.Policy(room, "left") + room.aimError("left", level)
+			room.driveAI("left", level)
+			want := min(level.MaxSpeed, int(math.Round(float64(target)*level.Reaction)))
+			if room.PanYLeft != want {
+				t.Fatalf("paddle moved to %d, want %d", room.PanYLeft, want)
+			}
+		})
+	}
+}
+
+func TestAIAimErrorRolledOnHit(t *testing.T) {
+	level := AILevel{Policy: (*Room).trackBall, Reaction: 1, MaxSpeed: 1000, Error: 20}
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+
+	errs := make(map[int]bool)
+	for hits := range 50 {
+		room.RallyHits = hits
+		aim := room.aimError("left", level)
+		if aim < -level.Error || aim > level.Error {
+			t.Fatalf("aimed %d off, want at most %d", aim, level.Error)
+		}
+		if again := room.aimError("left", level); again != aim {
+			t.Fatalf("aim moved from %d to %d without a hit", aim, again)
+		}
+		errs[aim] = true
+	}
+	if len(errs) < 10 {
+		t.Fatalf("only %d different aims over 50 hits", len(errs))
+	}
+	if aim := room.aimError("left", aiLevels["hard"]); aim != 0 {
+		t.Fatalf("hard AI aimed %d off, want 0", aim)
+	}
+}
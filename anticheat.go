@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"time"
+)
+
+// Farthest an absolute move may take a paddle per broadcast interval since
+// the player's previous move. Real input never comes close; a client jumping
+// its paddle across the board in one message is cheating or broken.
+var maxPaddleJump = flag.Int("max-paddle-jump", 100, "most pixels a paddle may move per broadcast interval on absolute moves; longer jumps are cut short (0 disables)")
+
+// Most broadcast intervals' worth of travel one move may use up, however
+// long the player sat still before it; otherwise idling would bank a teleport
+const maxJumpIntervals = 2
+
+// limitJump cuts an absolute move from one paddle position to another short
+// if the paddle couldn't have travelled that far since the player's last
+// move, logging the ones it cuts. Caller must hold room lock.
+func (room *Room) limitJump(player string, from, to int, now time.Time) int {
+	if *maxPaddleJump <= 0 {
+		return to
+	}
+	intervals := 1.0
+	if last, ok := room.lastMoveAt[player]; ok {
+		intervals = min(max(intervals, float64(now.Sub(last))/float64(room.Config.BroadcastInterval())), maxJumpIntervals)
+	}
+	if room.lastMoveAt == nil {
+		room.lastMoveAt = make(map[string]time.Time)
+	}
+	room.lastMoveAt[player] = now
+
+	allowed := int(float64(*maxPaddleJump) * intervals)
+	switch {
+	case to > from+allowed:
+		slog.Warn("Paddle jump cut short", "room", room.ID, "player", player, "from", from, "to", to, "allowed", allowed)
+		return from + allowed
+	case to < from-allowed:
+		slog.Warn("Paddle jump cut short", "room", room.ID, "player", player, "from", from, "to", to, "allowed", allowed)
+		return from - allowed
+	}
+	return to
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaddleSlideAppliedJumpCutShort(t *testing.T) {
+	setFlag(t, maxPaddleJump, 100)
+	room := newSteppedTestRoom(t)
+	logs := captureLogs(t)
+	interval := room.Config.BroadcastInterval()
+	now := time.Now()
+
+	// A legitimate slide: 50px a broadcast interval, all the way down
+	y := 0
+	for target := 50; target <= room.Config.MaxPaddleY(); target += 50 {
+		now = now.Add(interval)
+		if y = room.limitJump("left", y, target, now); y != target {
+			t.Fatalf("slide to %d stopped at %d", target, y)
+		}
+	}
+	if strings.Contains(logs.String(), "Paddle jump cut short") {
+		t.Fatalf("legitimate slide flagged:\n%s", logs.String())
+	}
+
+	// An instant jump back to the top only gets as far as one interval allows
+	now = now.Add(interval)
+	if got, want := room.limitJump("left", y, 0, now), y-*maxPaddleJump; got != want {
+		t.Fatalf("jump from %d to 0 applied as %d, want %d", y, got, want)
+	}
+	if !strings.Contains(logs.String(), "Paddle jump cut short") {
+		t.Fatal("cut-short jump wasn't logged")
+	}
+
+	// Sitting still doesn't bank more than maxJumpIntervals of travel
+	now = now.Add(10 * interval)
+	if got := room.limitJump("right", 0, room.Config.MaxPaddleY(), now); got != 100 {
+		t.Fatalf("first move of the other player applied as %d, want 100", got)
+	}
+	now = now.Add(10 * interval)
+	if got, want := room.limitJump("right", 100, room.Config.MaxPaddleY(), now), 100+maxJumpIntervals*100; got != want {
+		t.Fatalf("move after ten idle intervals applied as %d, want %d", got, want)
+	}
+	now = now.Add(interval * 3 / 2)
+	if got, want := room.limitJump("right", 300, room.Config.MaxPaddleY(), now), 450; got != want {
+		t.Fatalf("move after one and a half intervals applied as %d, want %d", got, want)
+	}
+}
+
+func TestPaddleJumpLimitDisabled(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	room := newSteppedTestRoom(t)
+	if got := room.limitJump("left", 0, room.Config.MaxPaddleY(), time.Now()); got != room.Config.MaxPaddleY() {
+		t.Fatalf("jump with the limit disabled applied as %d, want %d", got, room.Config.MaxPaddleY())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// BallBehavior adjusts the ball's velocity once per tick, before it moves
+type BallBehavior func(b *Ball)
+
+// Ball physics the host can pick between
+var ballBehaviors = map[string]BallBehavior{
+	"normal":  func(b *Ball) {},
+	"gravity": gravityBehavior,
+	"curve":   curveBehavior,
+}
+
+// Downward pull per tick in gravity physics
+const gravityPull = 0.15
+
+// Radians the velocity turns per tick in curve physics
+const curveRate = 0.01
+
+// gravityBehavior pulls the ball toward the bottom wall
+func gravityBehavior(b *Ball) {
+	b.Vy += gravityPull
+}
+
+// curveBehavior bends the ball's path into a gentle arc, curling the same
+// way relative to its direction of travel for both players
+func curveBehavior(b *Ball) {
+	angle := curveRate
+	if b.Vx < 0 {
+		angle = -angle
+	}
+	sin, cos := math.Sincos(angle)
+	b.Vx, b.Vy = b.Vx*cos-b.Vy*sin, b.Vx*sin+b.Vy*cos
+}
+
+// requestPhysics queues a physics change from the host; it takes effect at
+// the next serve so a rally in progress isn't disrupted. Caller must hold room lock.
+func (room *Room) requestPhysics(player, physics string) error {
+	if player != room.Host {
+		return fmt.Errorf("only the host (%s) can change physics", room.Host)
+	}
+	if _, ok := ballBehaviors[physics]; !ok {
+		return fmt.Errorf("unknown physics %q", physics)
+	}
+	room.PendingPhysics = physics
+	slog.Info("Host switched physics from the next serve", "room", room.ID, "player", player, "physics", physics)
+	return nil
+}
+
+// applyPendingPhysics switches to the queued physics. Caller must hold room lock.
+func (room *Room) applyPendingPhysics() {
+	if room.PendingPhysics == "" {
+		return
+	}
+	room.Physics = room.PendingPhysics
+	room.PendingPhysics = ""
+	slog.Info("Ball physics changed", "room", room.ID, "physics", room.Physics)
+}
+
+// behavior returns the active ball behavior. Caller must hold room lock.
+func (room *Room) behavior() BallBehavior {
+	if b, ok := ballBehaviors[room.Physics]; ok {
+		return b
+	}
+	return ballBehaviors["normal"]
+}
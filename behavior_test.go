@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestPhysicsSwitchWaitsForNextServe(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	room.Host = "left"
+	center := Ball{X: float64(room.Config.Width) / 2, Y: float64(room.Config.Height) / 2, Vx: 3}
+
+	// Mid-rally the ball keeps its course
+	room.Ball = center
+	if err := room.requestPhysics("left", "gravity"); err != nil {
+		t.Fatal(err)
+	}
+	if room.Physics == "gravity" || room.PendingPhysics != "gravity" {
+		t.Fatalf("physics %q pending %q right after the switch, want gravity pending", room.Physics, room.PendingPhysics)
+	}
+	for range 5 {
+		room.stepBall(&room.Ball)
+	}
+	if room.Ball.Vy != 0 {
+		t.Fatalf("ball picked up Vy %v before the next serve", room.Ball.Vy)
+	}
+
+	// From the next serve it falls
+	room.resetGame("right")
+	if room.Physics != "gravity" || room.PendingPhysics != "" {
+		t.Fatalf("physics %q pending %q after the serve, want gravity", room.Physics, room.PendingPhysics)
+	}
+	room.Ball = center
+	room.stepBall(&room.Ball)
+	if !near(room.Ball.Vy, gravityPull) {
+		t.Fatalf("ball Vy %v after one tick of gravity, want %v", room.Ball.Vy, gravityPull)
+	}
+}
+
+func TestPhysicsSwitchRejected(t *testing.T) {
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	room.Host = "left"
+	if err := room.requestPhysics("right", "curve"); err == nil {
+		t.Error("a player who isn't the host switched physics")
+	}
+	if err := room.requestPhysics("left", "bouncy"); err == nil {
+		t.Error("host switched to unknown physics")
+	}
+	if room.PendingPhysics != "" {
+		t.Errorf("rejected switches left %q pending", room.PendingPhysics)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/gorilla/websocket"
+)
+
+// Clients opt into binary position frames with /ws?proto=binary. Everything
+// else, including full updates, stays JSON; binary frames replace the deltas
+// sent between full updates.
+const (
+	JSONProtocol   = "json"
+	BinaryProtocol = "binary"
+)
+
+// Binary frame types, the first byte of every binary message
+const BinaryPositions byte = 1
+
+// Layout of a positions frame, all little-endian:
+//
+//	0   uint8    frame type (BinaryPositions)
+//	1   int16    leftY
+//	3   int16    rightY
+//	5   float32  ballX
+//	9   float32  ballY
+//	13  float32  speed
+const positionsFrameSize = 17
+
+// Positions is the per-tick state carried by a binary frame
+type Positions struct {
+	LeftY  int
+	RightY int
+	BallX  float64
+	BallY  float64
+	Speed  float64
+}
+
+// encodePositions packs positions into a binary frame
+func encodePositions(p Positions) []byte {
+	b := make([]byte, positionsFrameSize)
+	b[0] = BinaryPositions
+	binary.LittleEndian.PutUint16(b[1:], uint16(int16(p.LeftY)))
+	binary.LittleEndian.PutUint16(b[3:], uint16(int16(p.RightY)))
+	binary.LittleEndian.PutUint32(b[5:], math.Float32bits(float32(p.BallX)))
+	binary.LittleEndian.PutUint32(b[9:], math.Float32bits(float32(p.BallY)))
+	binary.LittleEndian.PutUint32(b[13:], math.Float32bits(float32(p.Speed)))
+	return b
+}
+
+// decodePositions unpacks a binary positions frame
+func decodePositions(b []byte) (Positions, error) {
+	if len(b) != positionsFrameSize {
+		return Positions{}, fmt.Errorf("positions frame is %d bytes, want %d", len(b), positionsFrameSize)
+	}
+	if b[0] != BinaryPositions {
+		return Positions{}, fmt.Errorf("unknown binary frame type %d", b[0])
+	}
+	return Positions{
+		LeftY:  int(int16(binary.LittleEndian.Uint16(b[1:]))),
+		RightY: int(int16(binary.LittleEndian.Uint16(b[3:]))),
+		BallX:  float64(math.Float32frombits(binary.LittleEndian.Uint32(b[5:]))),
+		BallY:  float64(math.Float32frombits(binary.LittleEndian.Uint32(b[9:]))),
+		Speed:  float64(math.Float32frombits(binary.LittleEndian.Uint32(b[13:]))),
+	}, nil
+}
+
+// writeBinary sends a binary frame to every binary client. Caller must hold clientsMutex.
+func (room *Room) writeBinary(frame []byte, action string, skip func(*websocket.Conn) bool) {
+	for client := range room.binaryClients {
+		if skip != nil && skip(client) {
+			continue
+		}
+		if err := room.sendTo(client, websocket.BinaryMessage, frame); err != nil {
+			room.dropClient(client, err, action)
+		}
+	}
+}
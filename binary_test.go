@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestPositionsRoundTrip(t *testing.T) {
+	for _, p := range []Positions{
+		{},
+		{LeftY: 250, RightY: 0, BallX: 400, BallY: 300, Speed: 7.0710678},
+		{LeftY: -1, RightY: 32767, BallX: 0.5, BallY: 599.25, Speed: 15},
+		{LeftY: -32768, RightY: 500, BallX: -10, BallY: 1e6, Speed: 0.001},
+	} {
+		frame := encodePositions(p)
+		if len(frame) != positionsFrameSize || frame[0] != BinaryPositions {
+			t.Fatalf("encoded %+v as % x, want a %d-byte positions frame", p, frame, positionsFrameSize)
+		}
+		got, err := decodePositions(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Coordinates travel as float32
+		want := Positions{LeftY: p.LeftY, RightY: p.RightY, BallX: float64(float32(p.BallX)), BallY: float64(float32(p.BallY)), Speed: float64(float32(p.Speed))}
+		if got != want {
+			t.Errorf("round trip of %+v gave %+v", p, got)
+		}
+		if again := encodePositions(got); !bytes.Equal(again, frame) {
+			t.Errorf("re-encoding %+v gave % x, want % x", got, again, frame)
+		}
+	}
+}
+
+func TestPositionsLayout(t *testing.T) {
+	frame := encodePositions(Positions{LeftY: 0x0102, RightY: -2, BallX: 1, BallY: 2, Speed: -1})
+	want := []byte{
+		BinaryPositions,
+		0x02, 0x01, // leftY
+		0xfe, 0xff, // rightY
+		0x00, 0x00, 0x80, 0x3f, // ballX 1.0
+		0x00, 0x00, 0x00, 0x40, // ballY 2.0
+		0x00, 0x00, 0x80, 0xbf, // speed -1.0
+	}
+	if !bytes.Equal(frame, want) {
+		t.Fatalf("frame % x, want % x", frame, want)
+	}
+}
+
+func TestDecodePositionsRejectsBadFrames(t *testing.T) {
+	good := encodePositions(Positions{LeftY: 1})
+	wrongType := bytes.Clone(good)
+	wrongType[0] = BinaryPositions + 1
+	for name, frame := range map[string][]byte{
+		"empty":      nil,
+		"short":      good[:positionsFrameSize-1],
+		"long":       append(bytes.Clone(good), 0),
+		"wrong type": wrongType,
+	} {
+		if _, err := decodePositions(frame); err == nil {
+			t.Errorf("%s frame decoded", name)
+		}
+	}
+}
+
+func TestBinaryClientGetsPositionFrames(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "?proto=binary&ai=easy")
+	readUntil(t, conn, AssignMessage)
+
+	deadline := time.Now().Add(testTimeout)
+	for {
+		conn.SetReadDeadline(deadline)
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("no binary frame before %v", err)
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+		p, err := decodePositions(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BallX < 0 || p.BallX > float64(cfg.Width) || p.BallY < 0 || p.BallY > float64(cfg.Height) {
+			t.Fatalf("binary frame put the ball at (%v, %v), off the court", p.BallX, p.BallY)
+		}
+		return
+	}
+}
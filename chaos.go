@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"time"
+)
+
+// Effect kinds
+const (
+	MirrorEffect  = "mirror"  // Inverts the player's paddle controls
+	EnlargeEffect = "enlarge" // Lengthens the player's paddle
+	ShrinkEffect  = "shrink"  // Shortens the player's paddle
+)
+
+// Effect is a timed modifier applied to one player's paddle
+type Effect struct {
+	Kind      string    `json:"kind"`
+	Player    string    `json:"player"`
+	Remaining int64     `json:"remaining"` // Milliseconds left, filled in when broadcast
+	Expires   time.Time `json:"-"`
+}
+
+// Chaos options
+var (
+	chaosMirror    = flag.Bool("chaos-mirror", false, "randomly mirror the opponent's controls when a player returns the ball")
+	mirrorChance   = flag.Float64("mirror-chance", 0.25, "probability that a paddle hit mirrors the opponent's controls")
+	mirrorDuration = flag.Duration("mirror-duration", 5*time.Second, "how long mirrored controls last")
+)
+
+// applyEffect starts (or refreshes) an effect on a player. Caller must hold room lock.
+func (room *Room) applyEffect(kind, player string, d time.Duration) {
+	expires := room.now().Add(d)
+	for i, e := range room.Effects {
+		if e.Kind == kind && e.Player == player {
+			room.Effects[i].Expires = expires
+			return
+		}
+	}
+	room.Effects = append(room.Effects, Effect{Kind: kind, Player: player, Expires: expires})
+	slog.Info("Applied effect", "room", room.ID, "effect", kind, "player", player, "duration", d)
+}
+
+// hasEffect reports whether a player is currently under an effect. Caller must hold room lock.
+func (room *Room) hasEffect(kind, player string) bool {
+	now := room.now()
+	for _, e := range room.Effects {
+		if e.Kind == kind && e.Player == player && now.Before(e.Expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// expireEffects drops effects whose window has passed. Caller must hold room lock.
+func (room *Room) expireEffects(now time.Time) {
+	active := room.Effects[:0]
+	for _, e := range room.Effects {
+		if now.Before(e.Expires) {
+			active = append(active, e)
+		} else {
+			slog.Info("Expired effect", "room", room.ID, "effect", e.Kind, "player", e.Player)
+		}
+	}
+	room.Effects = active
+}
+
+// activeEffects returns a copy of the active effects with remaining time filled in. Caller must hold room lock.
+func (room *Room) activeEffects(now time.Time) []Effect {
+	var effects []Effect
+	for _, e := range room.Effects {
+		if now.Before(e.Expires) {
+			e.Remaining = e.Expires.Sub(now).Milliseconds()
+			effects = append(effects, e)
+		}
+	}
+	return effects
+}
+
+// mirrorYPosition reflects a requested move around the court centre so up becomes down
+func (c Config) mirrorYPosition(target int) int {
+	return c.clampYPosition(c.MaxPaddleY() - target)
+}
+
+// maybeMirrorOpponent rolls the chaos mirror debuff after a paddle hit. Caller must hold room lock.
+func (room *Room) maybeMirrorOpponent(hitter string) {
+	if !*chaosMirror || room.rand.Float64() >= *mirrorChance {
+		return
+	}
+	room.applyEffect(MirrorEffect, opponent(hitter), *mirrorDuration)
+}
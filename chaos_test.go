@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMirrorYPosition(t *testing.T) {
+	cfg := Config{Height: 600, PaddleHeight: 100}
+	tests := []struct {
+		target, want int
+	}{
+		{target: 0, want: 500},
+		{target: 500, want: 0},
+		{target: 250, want: 250},
+		{target: 100, want: 400},
+		// Out-of-range targets still land on the court
+		{target: -50, want: 500},
+		{target: 900, want: 0},
+	}
+	for _, tt := range tests {
+		if got := cfg.mirrorYPosition(tt.target); got != tt.want {
+			t.Errorf("mirrorYPosition(%d) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorInvertsMoves(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	cfg := testConfig(t)
+	s, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	room := s.lookupRoom(DefaultRoomID)
+	room.Lock()
+	start := room.PanYLeft
+	room.applyEffect(MirrorEffect, "left", time.Minute)
+	room.Unlock()
+
+	// Asking to go to the top sends the paddle to the bottom
+	up := 0
+	if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &up}); err != nil {
+		t.Fatal(err)
+	}
+	var y int
+	waitFor(t, "the paddle to move", func() bool {
+		room.Lock()
+		defer room.Unlock()
+		y = room.PanYLeft
+		return y != start
+	})
+	if y != cfg.MaxPaddleY() {
+		t.Fatalf("paddle moved from %d to %d asking for the top, want the bottom at %d", start, y, cfg.MaxPaddleY())
+	}
+}
+
+func TestMirroredPaddlesReachBothEdges(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	cfg := testConfig(t)
+	s, ts := newTestServer(t, cfg)
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+
+	room := s.lookupRoom(DefaultRoomID)
+	room.Lock()
+	room.applyEffect(MirrorEffect, "left", time.Minute)
+	room.applyEffect(MirrorEffect, "right", time.Minute)
+	room.Unlock()
+
+	bottom := cfg.MaxPaddleY()
+	for _, tt := range []struct {
+		side   string
+		conn   *websocket.Conn
+		target int
+		want   int
+	}{
+		{"left", left, 0, bottom},
+		{"left", left, bottom, 0},
+		{"right", right, bottom, 0},
+		{"right", right, 0, bottom},
+	} {
+		if err := tt.conn.WriteJSON(Message{Type: MoveMessage, Y: &tt.target}); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, fmt.Sprintf("the %s paddle to reach %d asking for %d", tt.side, tt.want, tt.target), func() bool {
+			room.Lock()
+			defer room.Unlock()
+			if tt.side == "left" {
+				return room.PanYLeft == tt.want
+			}
+			return room.PanYRight == tt.want
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest chat message relayed, in characters; longer ones are rejected
+var maxChatLength = flag.Int("max-chat-length", 200, "longest chat message relayed, in characters")
+
+// Chat messages and emotes a connection may send per second, and back to back
+const (
+	maxChatPerSecond = 1
+	chatBurst        = 5
+)
+
+// Emotes clients can send with a tap instead of typing
+var emotes = map[string]bool{
+	"gg":    true,
+	"nice":  true,
+	"oops":  true,
+	"wow":   true,
+	"laugh": true,
+	"angry": true,
+}
+
+// newChatLimiter returns a limiter for chat messages and emotes
+func newChatLimiter(now time.Time) *rateLimiter {
+	return newRateLimiter(now, maxChatPerSecond, chatBurst)
+}
+
+// cleanChatText trims a chat message and checks it can be relayed
+func cleanChatText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("chat text is empty")
+	}
+	if n := utf8.RuneCountInString(text); n > *maxChatLength {
+		return "", fmt.Errorf("chat text is %d characters, more than %d", n, *maxChatLength)
+	}
+	for _, r := range text {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("chat text contains unprintable characters")
+		}
+	}
+	return text, nil
+}
+
+// relayChat sends a chat message or emote to everyone in the room, stamped
+// with the sender's verified role and name
+func (room *Room) relayChat(msgType, sender, name, text, emote string) {
+	msg := Message{Type: msgType, Player: sender, Name: name, Text: text, Emote: emote}
+	room.server.stampEvent(&msg)
+	room.broadcast(msg)
+}
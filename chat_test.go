@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRelayedChatIsStamped(t *testing.T) {
+	_, ts := newTestServer(t, testConfig(t))
+	sender := dialTest(t, ts, "?name=alice")
+	readUntil(t, sender, AssignMessage)
+	listener := dialTest(t, ts, "?name=bob")
+	readUntil(t, listener, AssignMessage)
+
+	texts := []string{"hello", "good luck", "gg"}
+	for _, text := range texts {
+		if err := sender.WriteJSON(Message{Type: ChatMessage, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var last Message
+	for _, text := range texts {
+		msg := readUntil(t, listener, ChatMessage)
+		if msg.Text != text || msg.Player != "left" || msg.Name != "alice" {
+			t.Fatalf("got chat %q from %s (%s), want %q from left (alice)", msg.Text, msg.Player, msg.Name, text)
+		}
+		if msg.Seq <= last.Seq || msg.Time == 0 || msg.Time < last.Time {
+			t.Fatalf("chat stamped seq %d at %d after seq %d at %d, want both to increase", msg.Seq, msg.Time, last.Seq, last.Time)
+		}
+		last = msg
+	}
+}
+
+func TestCleanChatText(t *testing.T) {
+	setFlag(t, maxChatLength, 10)
+	tests := []struct {
+		text, want string
+		wantErr    bool
+	}{
+		{text: "  hi there ", want: "hi there"},
+		{text: "0123456789", want: "0123456789"},
+		{text: "01234567890", wantErr: true},
+		{text: "   ", wantErr: true},
+		{text: "bell\a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := cleanChatText(tt.text)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cleanChatText(%q) = %q, %v; want %q, error %v", tt.text, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestBallHitsRectBoundary(t *testing.T) {
+	// A paddle-sized rectangle with its top-left corner at (100, 200)
+	const x, y, w, h = 100.0, 200.0, 10.0, 100.0
+	tests := []struct {
+		name   string
+		bx, by float64
+		want   bool
+	}{
+		{"touching the face", x + w + BallRadius, y + h/2, true},
+		{"just clear of the face", x + w + BallRadius + 1e-9, y + h/2, false},
+		{"touching the top edge", x + w/2, y - BallRadius, true},
+		{"just clear of the top edge", x + w/2, y - BallRadius - 1e-9, false},
+		// 6-8-10: the center is exactly a radius from the corner
+		{"touching the top corner", x + w + 6, y - 8, true},
+		{"touching the bottom corner", x + w + 6, y + h + 8, true},
+		{"just clear of the corner", x + w + 6 + 1e-9, y - 8, false},
+		// Within a radius of both edges' lines, but not of the corner itself
+		{"off the corner diagonal", x + w + 8, y - 8, false},
+		{"overlapping", x + w/2, y + h/2, true},
+	}
+	for _, tt := range tests {
+		ball := &Ball{X: tt.bx, Y: tt.by}
+		if got := ballHitsRect(ball, x, y, w, h); got != tt.want {
+			t.Errorf("%s: ball at (%v, %v) hit = %v, want %v", tt.name, tt.bx, tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestBallBouncesOffWallsWithinBounds(t *testing.T) {
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	height := float64(room.Config.Height)
+	for _, vy := range []float64{-3, -17, -BallRadius * 3, 3, 17, BallRadius * 3} {
+		room.Ball = Ball{X: float64(room.Config.Width) / 2, Y: height / 2, Vx: 0.5, Vy: vy}
+		bounced := false
+		for tick := 0; tick < 200 && !bounced; tick++ {
+			if conceded := room.stepBall(&room.Ball); conceded != "" {
+				t.Fatalf("Vy %v: %s conceded off a wall", vy, conceded)
+			}
+			if y := room.Ball.Y; y < BallRadius || y > height-BallRadius {
+				t.Fatalf("Vy %v: ball at y=%v, past the wall", vy, y)
+			}
+			bounced = room.Ball.Vy == -vy
+		}
+		if !bounced {
+			t.Errorf("Vy %v: ball didn't come back off the wall", vy)
+		}
+	}
+}
+
+func TestFastBallBouncesOffPaddle(t *testing.T) {
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	cfg := room.Config
+	room.PanYLeft, room.PanYRight = cfg.MaxPaddleY()/2, cfg.MaxPaddleY()/2
+	centerY := float64(cfg.MaxPaddleY()/2) + float64(cfg.PaddleHeight)/2
+	for _, side := range []string{"left", "right"} {
+		// Fired from 45px out at 30px a tick, so one tick ends it behind the paddle face
+		room.Ball = Ball{X: float64(cfg.PaddleWidth) + BallRadius + 45, Y: centerY, Vx: -30}
+		if side == "right" {
+			room.Ball.X, room.Ball.Vx = float64(cfg.Width)-room.Ball.X, 30
+		}
+		for range 3 {
+			if conceded := room.stepBall(&room.Ball); conceded != "" {
+				t.Fatalf("ball at 30px/tick went through the %s paddle", side)
+			}
+		}
+		if (side == "left") != (room.Ball.Vx > 0) {
+			t.Fatalf("ball left the %s paddle with Vx %v, want it sent back", side, room.Ball.Vx)
+		}
+	}
+}
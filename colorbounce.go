@@ -0,0 +1,22 @@
+package main
+
+import "flag"
+
+// Color-bounce mode recolors the ball on every bounce
+var colorBounce = flag.Bool("color-bounce", false, "give the ball a new random color on every bounce")
+
+// Colors the ball can take in color-bounce mode
+var ballColors = []string{"#ff0000", "#ff8800", "#ffff00", "#00ff00", "#00ffff", "#0088ff", "#ff00ff", "#ffffff"}
+
+// onBounce runs per-bounce effects on a ball. Caller must hold room lock.
+func (room *Room) onBounce(ball *Ball) {
+	if !*colorBounce {
+		return
+	}
+	// Always pick a different color so the change is visible
+	next := ballColors[room.rand.Intn(len(ballColors)-1)]
+	if next == ball.Color {
+		next = ballColors[len(ballColors)-1]
+	}
+	ball.Color = next
+}
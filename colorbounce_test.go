@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// bounceColors bounces a seeded room's ball off the top and bottom walls and
+// returns the color it took each time
+func bounceColors(t *testing.T, bounces int) []string {
+	t.Helper()
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	ball := &room.Ball
+	*ball = Ball{X: float64(room.Config.Width) / 2, Y: BallRadius + 1, Vy: -5}
+	var colors []string
+	for range bounces {
+		previous := ball.Color
+		room.stepBall(ball)
+		if ball.Color == previous {
+			t.Fatalf("ball stayed %q after bouncing", ball.Color)
+		}
+		colors = append(colors, ball.Color)
+		// On to the other wall, which it reaches next tick
+		if ball.Vy > 0 {
+			ball.Y = float64(room.Config.Height) - BallRadius - 1
+		} else {
+			ball.Y = BallRadius + 1
+		}
+	}
+	return colors
+}
+
+func TestColorBounceDeterministic(t *testing.T) {
+	setFlag(t, colorBounce, true)
+	first := bounceColors(t, 10)
+	for _, color := range first {
+		if !slices.Contains(ballColors, color) {
+			t.Fatalf("ball colored %q, not one of %v", color, ballColors)
+		}
+	}
+	if second := bounceColors(t, 10); !slices.Equal(first, second) {
+		t.Fatalf("same seed colored the ball %v, then %v", first, second)
+	}
+}
+
+func TestColorBounceBroadcast(t *testing.T) {
+	setFlag(t, colorBounce, true)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "?ai=easy")
+	readUntil(t, conn, AssignMessage)
+
+	for {
+		msg := readUntil(t, conn, UpdateMessage)
+		if msg.BallColor != "" {
+			return
+		}
+	}
+}
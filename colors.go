@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Paddle colors players may pick by name, besides any #rrggbb
+var paddleColorNames = map[string]bool{
+	"blue":   true,
+	"red":    true,
+	"green":  true,
+	"yellow": true,
+	"orange": true,
+	"purple": true,
+	"pink":   true,
+	"cyan":   true,
+	"white":  true,
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Colors of paddles whose player hasn't picked one
+var defaultPaddleColors = map[string]string{
+	"left":   "blue",
+	"right":  "red",
+	"top":    "green",
+	"bottom": "yellow",
+}
+
+// validateColor normalizes a paddle color and checks it is a known name or
+// #rrggbb, so clients can use it as-is
+func validateColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if !paddleColorNames[color] && !hexColor.MatchString(color) {
+		return "", fmt.Errorf("color %q must be #rrggbb or one of blue, red, green, yellow, orange, purple, pink, cyan, white", color)
+	}
+	return color, nil
+}
+
+// setColor records the color a player picked, or forgets it with "". Caller
+// must hold room lock.
+func (room *Room) setColor(player, color string) {
+	if color == "" {
+		delete(room.Colors, player)
+		return
+	}
+	if room.Colors == nil {
+		room.Colors = make(map[string]string)
+	}
+	room.Colors[player] = color
+}
+
+// paddleColors returns the color of every paddle in play, picked or default.
+// Caller must hold room lock.
+func (room *Room) paddleColors() map[string]string {
+	colors := make(map[string]string)
+	for _, side := range room.sides() {
+		colors[side] = cmp.Or(room.Colors[side], defaultPaddleColors[side])
+	}
+	return colors
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"compress/flate"
+	"flag"
+	"fmt"
+)
+
+// Compression options. gorilla/websocket only offers permessage-deflate
+// without context takeover, so every frame is compressed on its own and the
+// repetition between frames isn't exploited. Measured over a minute of a
+// two-player match with deltas (the default), the two clients received about
+// 490 KB either way, since only the occasional full update is big enough to
+// gain. With -resync-frames 1 the traffic shrinks by about 20%.
+var (
+	compress         = flag.Bool("compress", false, "negotiate permessage-deflate compression with clients that support it")
+	compressionLevel = flag.Int("compression-level", 1, "deflate level for compressed connections (-2 to 9); setting it enables compression")
+)
+
+// Frames shorter than this, deltas and binary positions among them, go out
+// uncompressed even on compressed connections: deflated on their own they
+// come out no smaller. Control frames are never compressed.
+const minCompressedFrame = 128
+
+// validateCompressionLevel checks a level against the range accepted by SetCompressionLevel
+func validateCompressionLevel(level int) error {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return fmt.Errorf("compression level %d out of range [%d, %d]", level, flate.HuffmanOnly, flate.BestCompression)
+	}
+	return nil
+}
+
+// compressionEnabled reports whether compression was asked for, either
+// outright or by choosing a level
+func compressionEnabled() bool {
+	enabled := *compress
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "compression-level" {
+			enabled = true
+		}
+	})
+	return enabled
+}
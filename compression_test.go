@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestValidateCompressionLevel(t *testing.T) {
+	for _, level := range []int{-2, -1, 0, 1, 6, 9} {
+		if err := validateCompressionLevel(level); err != nil {
+			t.Errorf("level %d rejected: %v", level, err)
+		}
+	}
+	for _, level := range []int{-3, 10, 100} {
+		if err := validateCompressionLevel(level); err == nil {
+			t.Errorf("level %d accepted", level)
+		}
+	}
+}
+
+func TestCompressedConnection(t *testing.T) {
+	setFlag(t, compressionLevel, 9)
+	stats, err := openJSONStatsStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(testConfig(t), stats)
+	s.upgrader.EnableCompression = true
+	ts := httptest.NewServer(s.Handler(nil))
+	t.Cleanup(func() {
+		s.closeConnections()
+		ts.Close()
+	})
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(wsURL(ts, ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Fatalf("negotiated extensions %q, want permessage-deflate", ext)
+	}
+	// The assign message is long enough to go out compressed
+	if msg := readUntil(t, conn, AssignMessage); msg.Player != "left" || msg.Config == nil {
+		t.Fatalf("got assign %+v over a compressed connection, want left with the config", msg)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Config is the board geometry and timing shared by every room
+type Config struct {
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	PaddleWidth  int     `json:"paddleWidth"`
+	PaddleHeight int     `json:"paddleHeight"`
+	TickMs       int     `json:"tickMs"`
+	BroadcastMs  int     `json:"broadcastMs"`
+	BallSpeed    float64 `json:"ballSpeed"`   // Initial serve speed on each axis
+	PaddleSpeed  float64 `json:"paddleSpeed"` // Pixels per second a paddle moves under velocity input
+	BallStartX   float64 `json:"ballStartX"`  // Where the ball is served from in classic mode
+	BallStartY   float64 `json:"ballStartY"`
+}
+
+// Board options. The defaults are the original fixed 800x600 board at ~60 FPS,
+// broadcasting every tick.
+var (
+	boardWidth   = flag.Int("width", 800, "canvas width in pixels")
+	boardHeight  = flag.Int("height", 600, "canvas height in pixels")
+	paddleWidth  = flag.Int("paddle-width", 20, "paddle width in pixels")
+	paddleHeight = flag.Int("paddle-height", 100, "paddle height in pixels")
+	tickMs       = flag.Int("tickms", 16, "milliseconds between physics ticks")
+	broadcastMs  = flag.Int("broadcast-ms", 0, "milliseconds between game state broadcasts (0 broadcasts every physics tick)")
+	ballSpeed    = flag.Float64("ball-speed", 4, "initial ball speed in pixels per tick on each axis")
+	paddleSpeed  = flag.Float64("paddle-speed", 300, "pixels per second a paddle moves in velocity input mode, whatever the tick rate")
+	ballStartX   = flag.Float64("ball-start-x", -1, "X the ball is served from in classic mode, e.g. for drills (negative serves from the center)")
+	ballStartY   = flag.Float64("ball-start-y", -1, "Y the ball is served from in classic mode (negative serves from the center)")
+)
+
+// loadConfig builds and validates the configuration from the flags
+func loadConfig() (Config, error) {
+	c := Config{
+		Width:        *boardWidth,
+		Height:       *boardHeight,
+		PaddleWidth:  *paddleWidth,
+		PaddleHeight: *paddleHeight,
+		TickMs:       *tickMs,
+		BroadcastMs:  *broadcastMs,
+		BallSpeed:    *ballSpeed,
+		PaddleSpeed:  *paddleSpeed,
+		BallStartX:   *ballStartX,
+		BallStartY:   *ballStartY,
+	}
+	if c.BallStartX < 0 {
+		c.BallStartX = float64(c.Width / 2)
+	}
+	if c.BallStartY < 0 {
+		c.BallStartY = float64(c.Height / 2)
+	}
+	switch {
+	case c.PaddleWidth <= 0 || c.PaddleHeight <= 0:
+		return c, errors.New("paddle dimensions must be positive")
+	case c.Width <= 2*c.PaddleWidth:
+		return c, errors.New("width must leave room between the paddles")
+	case c.Height <= c.PaddleHeight:
+		return c, errors.New("height must be greater than the paddle height")
+	case c.Width <= c.PaddleHeight:
+		// Quad mode lays paddles flat along the top and bottom walls
+		return c, errors.New("width must be greater than the paddle height")
+	case c.TickMs <= 0:
+		return c, errors.New("tickms must be positive")
+	case c.BroadcastMs < 0:
+		return c, errors.New("broadcast-ms must not be negative")
+	case c.BallSpeed <= 0:
+		return c, errors.New("ball speed must be positive")
+	case c.PaddleSpeed <= 0:
+		return c, errors.New("paddle speed must be positive")
+	case c.BallStartX < float64(c.PaddleWidth+BallRadius) || c.BallStartX > float64(c.Width-c.PaddleWidth-BallRadius):
+		// Any closer and the ball would start touching a paddle's lane
+		return c, fmt.Errorf("ball-start-x must be between the paddles, %d-%d", c.PaddleWidth+BallRadius, c.Width-c.PaddleWidth-BallRadius)
+	case c.BallStartY < BallRadius || c.BallStartY > float64(c.Height-BallRadius):
+		return c, fmt.Errorf("ball-start-y must keep the ball off the walls, %d-%d", BallRadius, c.Height-BallRadius)
+	}
+	if c.BroadcastMs == 0 {
+		c.BroadcastMs = c.TickMs
+	}
+	return c, nil
+}
+
+// MaxPaddleY is the lowest a paddle's top edge may go
+func (c Config) MaxPaddleY() int {
+	return c.Height - c.PaddleHeight
+}
+
+// TickInterval is the time between physics ticks
+func (c Config) TickInterval() time.Duration {
+	return time.Duration(c.TickMs) * time.Millisecond
+}
+
+// BroadcastInterval is the time between game state broadcasts
+func (c Config) BroadcastInterval() time.Duration {
+	return time.Duration(c.BroadcastMs) * time.Millisecond
+}
+
+// clampYPosition keeps a paddle's Y on the board. At MaxPaddleY the paddle's
+// bottom edge lies exactly on the bottom of the board.
+func (c Config) clampYPosition(y int) int {
+	if y < 0 {
+		return 0
+	}
+	if y > c.MaxPaddleY() {
+		return c.MaxPaddleY()
+	}
+	return y
+}
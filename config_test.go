@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestClampYPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		y    int
+		want int
+	}{
+		{"negative", Config{Height: 600, PaddleHeight: 100}, -1, 0},
+		{"far above", Config{Height: 600, PaddleHeight: 100}, -1000, 0},
+		{"zero", Config{Height: 600, PaddleHeight: 100}, 0, 0},
+		{"mid-range", Config{Height: 600, PaddleHeight: 100}, 250, 250},
+		{"exactly max", Config{Height: 600, PaddleHeight: 100}, 500, 500},
+		{"just past max", Config{Height: 600, PaddleHeight: 100}, 501, 500},
+		{"at the canvas height", Config{Height: 600, PaddleHeight: 100}, 600, 500},
+		{"custom canvas", Config{Height: 300, PaddleHeight: 60}, 290, 240},
+		{"custom canvas mid-range", Config{Height: 300, PaddleHeight: 60}, 100, 100},
+	}
+	for _, tt := range tests {
+		got := tt.cfg.clampYPosition(tt.y)
+		if got != tt.want {
+			t.Errorf("%s: clampYPosition(%d) = %d, want %d", tt.name, tt.y, got, tt.want)
+		}
+		// No part of the paddle is ever drawn off the canvas
+		if got < 0 || got+tt.cfg.PaddleHeight > tt.cfg.Height {
+			t.Errorf("%s: paddle at %d spans %d-%d, off a %d high canvas", tt.name, got, got, got+tt.cfg.PaddleHeight, tt.cfg.Height)
+		}
+	}
+}
+
+func TestResetGameServesFromConfiguredStart(t *testing.T) {
+	setFlag(t, ballStartX, 200.0)
+	setFlag(t, ballStartY, 150.0)
+	setFlag(t, serveDelay, 0)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+
+	if room.Ball.X != 200 || room.Ball.Y != 150 {
+		t.Fatalf("new room's ball at (%v, %v), want (200, 150)", room.Ball.X, room.Ball.Y)
+	}
+	room.Ball.X, room.Ball.Y = 700, 40
+	room.resetGame("left")
+	if room.Ball.X != 200 || room.Ball.Y != 150 {
+		t.Fatalf("ball served from (%v, %v), want (200, 150)", room.Ball.X, room.Ball.Y)
+	}
+}
+
+func TestBallStartValidated(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   float64
+		wantOK bool
+	}{
+		{"center by default", -1, -1, true},
+		{"off center", 200, 150, true},
+		{"inside the left paddle's lane", 5, 150, false},
+		{"inside the right paddle's lane", 795, 150, false},
+		{"above the top wall", 200, 2, false},
+		{"below the bottom wall", 200, 10000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, ballStartX, tt.x)
+			setFlag(t, ballStartY, tt.y)
+			if _, err := loadConfig(); (err == nil) != tt.wantOK {
+				t.Fatalf("loadConfig() error %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// Token that unlocks /state. Empty leaves the endpoint unregistered, since
+// the state includes every client's address.
+var stateToken = flag.String("state-token", "", "serve full internal game state at /state to requests bearing this token (empty disables it)")
+
+// RoomState is everything a room knows about its game, for debugging desyncs
+type RoomState struct {
+	ID          string            `json:"id"`
+	Mode        string            `json:"mode"`
+	State       string            `json:"state"`
+	LeftY       int               `json:"leftY"`
+	RightY      int               `json:"rightY"`
+	TopX        int               `json:"topX"`
+	BottomX     int               `json:"bottomX"`
+	Ball        Ball              `json:"ball"`
+	ExtraBalls  []Ball            `json:"extraBalls,omitempty"`
+	ScoreLeft   int               `json:"scoreLeft"`
+	ScoreRight  int               `json:"scoreRight"`
+	Serving     string            `json:"serving,omitempty"`
+	Directions  map[string]int    `json:"directions,omitempty"`
+	Effects     []Effect          `json:"effects,omitempty"`
+	PowerUps    []PowerUp         `json:"powerUps,omitempty"`
+	Clients     int               `json:"clients"`     // Players and spectators
+	Assignments map[string]string `json:"assignments"` // Paddle by remote address
+	Queued      int               `json:"queued"`
+}
+
+// debugState copies the room's state without changing any of it
+func (room *Room) debugState() RoomState {
+	room.Lock()
+	defer room.Unlock()
+
+	state := RoomState{
+		ID:          room.ID,
+		Mode:        room.Mode,
+		State:       room.State,
+		LeftY:       room.PanYLeft,
+		RightY:      room.PanYRight,
+		TopX:        room.PanXTop,
+		BottomX:     room.PanXBottom,
+		Ball:        room.Ball,
+		ExtraBalls:  slices.Clone(room.ExtraBalls),
+		ScoreLeft:   room.ScoreLeft,
+		ScoreRight:  room.ScoreRight,
+		Serving:     room.Serving,
+		Directions:  maps.Clone(room.Directions),
+		Effects:     slices.Clone(room.Effects),
+		PowerUps:    slices.Clone(room.PowerUps),
+		Assignments: make(map[string]string),
+	}
+
+	room.queueMutex.Lock()
+	state.Queued = len(room.waitQueue)
+	room.queueMutex.Unlock()
+
+	room.assignMutex.Lock()
+	for conn, side := range room.assignedPlayers {
+		state.Assignments[conn.RemoteAddr().String()] = side
+	}
+	room.assignMutex.Unlock()
+
+	room.clientsMutex.Lock()
+	state.Clients = len(room.clients) + len(room.spectators)
+	room.clientsMutex.Unlock()
+	return state
+}
+
+// handleState serves the internal state of every room, or of the one named
+// by ?room=, to requests with the state token as a bearer token
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !bearerTokenMatches(r, *stateToken) {
+		slog.Warn("Refused state request", "remote_addr", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var rooms []*Room
+	if id := r.URL.Query().Get("room"); id != "" {
+		room := s.lookupRoom(id)
+		if room == nil {
+			http.Error(w, "no such room", http.StatusNotFound)
+			return
+		}
+		rooms = append(rooms, room)
+	} else {
+		rooms = s.activeRooms()
+	}
+
+	states := make([]RoomState, 0, len(rooms))
+	for _, room := range rooms {
+		states = append(states, room.debugState())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(states); err != nil {
+		slog.Error("Error encoding game state", "err", err)
+	}
+}
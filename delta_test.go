@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestLateJoinerGetsFullStateBeforeDeltas(t *testing.T) {
+	setFlag(t, scoringBand, 200)
+	setFlag(t, maxConnsPerIP, 0)
+	setFlag(t, serveDelay, 0)
+	s, ts := newTestServer(t, testConfig(t))
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+	room := s.lookupRoom(DefaultRoomID)
+	waitFor(t, "play to start", func() bool {
+		room.Lock()
+		defer room.Unlock()
+		return room.State == StatePlaying
+	})
+	// Deltas are flowing to the players by now
+	readUntil(t, left, DeltaMsg)
+
+	late := dialTest(t, ts, "")
+	readUntil(t, late, ErrorMessage)
+	if err := late.WriteJSON(Message{Type: JoinMessage, Option: JoinSpectate}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, late, AssignMessage)
+	first := readUntil(t, late, UpdateMessage)
+	if first.State != StatePlaying || first.Band == nil || first.Host != "left" || first.Physics == "" {
+		t.Fatalf("first frame %+v, want the full state: playing, with the band, host and physics", first)
+	}
+	for {
+		msg, err := readMessage(late)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case DeltaMsg:
+			t.Fatal("got a delta before a full update after joining")
+		case UpdateMessage:
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// EventSchemaVersion is bumped whenever the exported event format changes
+// incompatibly.
+//
+// Schema v2, one JSON object per line:
+//
+//	schema  int     always 2
+//	seq     uint64  monotonic sequence shared with relayed messages
+//	time    int64   server time in Unix milliseconds
+//	room    string  ID of the room the event happened in
+//	type    string  "join", "leave", "hit", "point" or "gameover"
+//	player  string  side involved ("left"/"right"), for join, leave, hit and point (the scorer);
+//	                "spectator" for spectators joining or leaving
+//	winner  string  winning side, for gameover
+//	addr    string  remote address, for join and leave
+//
+// v2 added room.
+const EventSchemaVersion = 2
+
+// Exported event types
+const (
+	JoinEvent     = "join"
+	LeaveEvent    = "leave"
+	HitEvent      = "hit"
+	PointEvent    = "point"
+	GameOverEvent = "gameover"
+)
+
+// Event is a single exported match event
+type Event struct {
+	Schema int    `json:"schema"`
+	Seq    uint64 `json:"seq"`
+	Time   int64  `json:"time"`
+	Room   string `json:"room"`
+	Type   string `json:"type"`
+	Player string `json:"player,omitempty"`
+	Winner string `json:"winner,omitempty"`
+	Addr   string `json:"addr,omitempty"`
+}
+
+// Destination for exported events: a file path, "-" for stdout, or empty to disable
+var eventsFile = flag.String("events-file", "", `append match events as JSON Lines to this file ("-" for stdout)`)
+
+// startEventExport opens the server's event sink, a file path or "-" for
+// stdout, and starts its writer
+func (s *Server) startEventExport(path string) error {
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		out, file = f, f
+	}
+
+	events, done := make(chan Event, 1024), make(chan struct{})
+	s.eventsMutex.Lock()
+	s.events, s.eventsDone = events, done
+	s.eventsMutex.Unlock()
+	go func() {
+		defer close(done)
+		writeEvents(out, events)
+		if file == nil {
+			return
+		}
+		if err := file.Close(); err != nil {
+			slog.Error("Error closing events file", "file", path, "err", err)
+		}
+	}()
+	slog.Info("Exporting match events", "file", path)
+	return nil
+}
+
+// closeEventExport stops exporting events and waits until those already
+// queued are written and the file is closed
+func (s *Server) closeEventExport() {
+	s.eventsMutex.Lock()
+	events, done := s.events, s.eventsDone
+	s.events = nil
+	s.eventsMutex.Unlock()
+	if events == nil {
+		return
+	}
+	close(events)
+	<-done
+}
+
+// writeEvents serializes events to out, one per line
+func writeEvents(out io.Writer, events <-chan Event) {
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for e := range events {
+		if err := enc.Encode(e); err != nil {
+			slog.Error("Error writing event", "err", err)
+			continue
+		}
+		// Flush once the queue drains so the file never lags far behind
+		if len(events) == 0 {
+			if err := w.Flush(); err != nil {
+				slog.Error("Error flushing events", "err", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		slog.Error("Error flushing events", "err", err)
+	}
+}
+
+// emitEvent queues an event for export without blocking the caller
+func (s *Server) emitEvent(e Event) {
+	s.eventsMutex.Lock()
+	defer s.eventsMutex.Unlock()
+	if s.events == nil {
+		return
+	}
+	e.Schema = EventSchemaVersion
+	e.Seq = s.eventSeq.Add(1)
+	e.Time = time.Now().UnixMilli()
+	select {
+	case s.events <- e:
+	default:
+		slog.Warn("Event export queue full, dropping event", "room", e.Room, "event_type", e.Type)
+	}
+}
+
+// emitEvent exports an event that happened in the room
+func (room *Room) emitEvent(e Event) {
+	e.Room = room.ID
+	room.server.emitEvent(e)
+}
+
+// stampEvent tags a relayed event with a server timestamp and sequence number
+// so clients can order and dedupe them
+func (s *Server) stampEvent(msg *Message) {
+	msg.Seq = s.eventSeq.Add(1)
+	msg.Time = time.Now().UnixMilli()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEventExportRecordsMatch(t *testing.T) {
+	setFlag(t, winningScore, 1)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	s, ts := newTestServer(t, cfg)
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := s.startEventExport(path); err != nil {
+		t.Fatal(err)
+	}
+
+	left := dialTest(t, ts, "?room=exported")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "?room=exported")
+	readUntil(t, right, AssignMessage)
+	over := playOut(t, cfg, GameOverMsg, left, right)[0]
+	left.Close()
+	right.Close()
+	waitFor(t, "the room to close", func() bool { return s.lookupRoom("exported") == nil })
+	s.closeEventExport()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Every field is known to the schema
+		dec := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		dec.DisallowUnknownFields()
+		var e Event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		if e.Schema != EventSchemaVersion || e.Room != "exported" || e.Time == 0 {
+			t.Fatalf("event %+v, want schema %d in room exported with a time", e, EventSchemaVersion)
+		}
+		if len(events) > 0 && e.Seq <= events[len(events)-1].Seq {
+			t.Fatalf("event %+v follows seq %d", e, events[len(events)-1].Seq)
+		}
+		events = append(events, e)
+	}
+
+	// Hits depend on where the ball happened to go
+	events = slices.DeleteFunc(events, func(e Event) bool { return e.Type == HitEvent })
+	want := []Event{
+		{Type: JoinEvent, Player: "left"},
+		{Type: JoinEvent, Player: "right"},
+		{Type: PointEvent, Player: over.Winner},
+		{Type: GameOverEvent, Winner: over.Winner},
+		{Type: LeaveEvent},
+		{Type: LeaveEvent},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got events %+v, want %+v", events, want)
+	}
+	for i, e := range events {
+		if e.Type != want[i].Type || want[i].Player != "" && e.Player != want[i].Player || e.Winner != want[i].Winner {
+			t.Fatalf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Admin controls for catching physics bugs in the act. /admin/pause freezes
+// a room: its game loop stops advancing it, but connections stay up, the
+// last state is still broadcast and /state still answers. While frozen,
+// /admin/step advances it by exactly one tick, as Step does for a stepped
+// room, and /admin/resume lets the game loop take over again. The room's
+// clock stands still while it is frozen, so countdowns, effects and clocks
+// pick up where they left off.
+
+// freeze stops the game loop advancing the room, reporting false if it was
+// already frozen. Caller must hold room lock.
+func (room *Room) freeze() bool {
+	if room.frozen {
+		return false
+	}
+	room.simTime = room.now()
+	room.frozen = true
+	return true
+}
+
+// thaw hands a frozen room back to the game loop, its clock running on from
+// the last step, reporting false if it wasn't frozen. Caller must hold room
+// lock.
+func (room *Room) thaw() bool {
+	if !room.frozen {
+		return false
+	}
+	room.frozen = false
+	room.clockLag = time.Since(room.simTime)
+	return true
+}
+
+// broadcastFrozen tells everyone play was halted or resumed by an
+// administrator
+func (room *Room) broadcastFrozen(frozen bool) {
+	msg := Message{Type: ResumedMsg, Hint: "An administrator resumed the game."}
+	if frozen {
+		msg = Message{Type: PausedMsg, Paused: true, Hint: "An administrator paused the game."}
+	}
+	room.server.stampEvent(&msg)
+	room.broadcast(msg)
+}
+
+// handleAdminPause freezes a room
+func (s *Server) handleAdminPause(w http.ResponseWriter, r *http.Request) {
+	room := s.adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	room.Lock()
+	froze := room.freeze()
+	room.Unlock()
+	if froze {
+		slog.Info("Admin paused room", "room", room.ID, "ip", clientIP(r))
+		room.broadcastFrozen(true)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminStep advances a frozen room by one tick and returns its state
+// afterwards
+func (s *Server) handleAdminStep(w http.ResponseWriter, r *http.Request) {
+	room := s.adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	room.Lock()
+	frozen := room.frozen
+	room.Unlock()
+	if !frozen {
+		http.Error(w, "room isn't paused", http.StatusConflict)
+		return
+	}
+	slog.Debug("Admin stepped room", "room", room.ID, "ip", clientIP(r))
+
+	room.Step()
+	room.broadcastGameState()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(room.debugState()); err != nil {
+		slog.Error("Error encoding room state", "room", room.ID, "err", err)
+	}
+}
+
+// handleAdminResume unfreezes a room
+func (s *Server) handleAdminResume(w http.ResponseWriter, r *http.Request) {
+	room := s.adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	room.Lock()
+	thawed := room.thaw()
+	room.Unlock()
+	if thawed {
+		slog.Info("Admin resumed room", "room", room.ID, "ip", clientIP(r))
+		room.broadcastFrozen(false)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package main
+
+import "math"
+
+// How far the ball's direction (degrees) or speed (fraction) may drift from
+// what clients were last told before updates carry its velocity again
+const (
+	velocityResendAngle = 5
+	velocityResendSpeed = 0.05
+)
+
+// ballVelocity returns the served ball's velocity in pixels per tick. Caller
+// must hold room lock.
+func (room *Room) ballVelocity() *Vector {
+	return &Vector{X: room.Ball.Vx, Y: room.Ball.Vy}
+}
+
+// velocityUpdate returns the ball's velocity if it changed enough since
+// clients last heard it, on a bounce, a serve or a stop, and nil otherwise.
+// Clients dead-reckon the ball between broadcasts from the last velocity
+// they got, so small changes such as spin curving it aren't worth a full
+// update each. Caller must hold room lock.
+func (room *Room) velocityUpdate() *Vector {
+	v := room.ballVelocity()
+	if !velocityChanged(room.sentVelocity, *v) {
+		return nil
+	}
+	room.sentVelocity = *v
+	return v
+}
+
+// velocityChanged reports whether a velocity differs noticeably from one
+// sent before
+func velocityChanged(sent, v Vector) bool {
+	sentSpeed, speed := math.Hypot(sent.X, sent.Y), math.Hypot(v.X, v.Y)
+	if sentSpeed == 0 || speed == 0 {
+		return sentSpeed != speed
+	}
+	if math.Abs(speed-sentSpeed) > sentSpeed*velocityResendSpeed {
+		return true
+	}
+	turn := math.Abs(math.Atan2(v.Y, v.X)-math.Atan2(sent.Y, sent.X)) * 180 / math.Pi
+	return math.Min(turn, 360-turn) > velocityResendAngle
+}
+
+// broadcastGameStart tells everyone play has begun, with where the ball is
+// and how it is moving. Must be called without holding room lock.
+func (room *Room) broadcastGameStart() {
+	room.Lock()
+	msg := Message{
+		Type:     GameStartMsg,
+		BallX:    room.Ball.X,
+		BallY:    room.Ball.Y,
+		Balls:    room.ballPositions(),
+		Velocity: room.ballVelocity(),
+	}
+	room.sentVelocity = *msg.Velocity
+	room.Unlock()
+
+	room.server.stampEvent(&msg)
+	room.broadcast(msg)
+}
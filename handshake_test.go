@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFailedHandshakeFreesPaddle(t *testing.T) {
+	setFlag(t, reconnectGrace, time.Minute)
+	s, ts := newTestServer(t, testConfig(t))
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	room := s.lookupRoom(DefaultRoomID)
+	logs := captureLogs(t)
+
+	// Hold the room so the next join stops between taking a paddle and
+	// being told about it, then break its connection
+	room.Lock()
+	dialTest(t, ts, "")
+	var joining *websocket.Conn
+	waitFor(t, "the right paddle to be taken", func() bool {
+		room.assignMutex.Lock()
+		defer room.assignMutex.Unlock()
+		for conn, side := range room.assignedPlayers {
+			if side == "right" {
+				joining = conn
+			}
+		}
+		return joining != nil
+	})
+	room.clientsMutex.Lock()
+	joining.Close()
+	room.sendTo(joining, websocket.TextMessage, []byte("{}"))
+	out := room.senders[joining]
+	room.clientsMutex.Unlock()
+	waitFor(t, "the write to fail", func() bool {
+		out.mu.Lock()
+		defer out.mu.Unlock()
+		return out.err != nil
+	})
+	room.Unlock()
+
+	waitFor(t, "the connection to be cleaned up", func() bool {
+		room.clientsMutex.Lock()
+		defer room.clientsMutex.Unlock()
+		_, sending := room.senders[joining]
+		_, client := room.clients[joining]
+		return !sending && !client
+	})
+	if !strings.Contains(logs.String(), `msg="Freed paddle of abandoned join"`) {
+		t.Fatalf("paddle not freed as an abandoned join:\n%s", logs.String())
+	}
+	// Not held for a reconnect either
+	next := dialTest(t, ts, "")
+	if msg := readUntil(t, next, AssignMessage); msg.Player != "right" {
+		t.Fatalf("next client assigned %q, want the freed right paddle", msg.Player)
+	}
+}
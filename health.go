@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// RoomHealth summarizes one room for /healthz
+type RoomHealth struct {
+	ID         string  `json:"id"`
+	State      string  `json:"state"`
+	Clients    int     `json:"clients"`
+	Players    int     `json:"players"`
+	Spectators int     `json:"spectators"`
+	BallX      float64 `json:"ballX"`
+	BallY      float64 `json:"ballY"`
+}
+
+// Health is the /healthz response
+type Health struct {
+	Status  string       `json:"status"`
+	Rooms   int          `json:"rooms"`
+	Clients int          `json:"clients"` // Players and spectators across all rooms
+	Players int          `json:"players"`
+	Summary []RoomHealth `json:"summary"`
+}
+
+// health summarizes the room
+func (room *Room) health() RoomHealth {
+	room.Lock()
+	defer room.Unlock()
+
+	h := RoomHealth{ID: room.ID, State: room.State, BallX: room.Ball.X, BallY: room.Ball.Y}
+
+	room.assignMutex.Lock()
+	h.Players = len(room.assignedPlayers)
+	room.assignMutex.Unlock()
+
+	room.clientsMutex.Lock()
+	h.Spectators = len(room.spectators)
+	h.Clients = len(room.clients) + h.Spectators
+	room.clientsMutex.Unlock()
+	return h
+}
+
+// handleHealth reports that the server is up and how busy it is
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	active := s.activeRooms()
+
+	health := Health{Status: "ok", Rooms: len(active), Summary: make([]RoomHealth, 0, len(active))}
+	for _, room := range active {
+		h := room.health()
+		health.Clients += h.Clients
+		health.Players += h.Players
+		health.Summary = append(health.Summary, h)
+	}
+	sort.Slice(health.Summary, func(i, j int) bool { return health.Summary[i].ID < health.Summary[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		slog.Error("Error encoding health", "err", err)
+	}
+}
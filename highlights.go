@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Longest rally buffer we keep, about two minutes at 60 FPS
+const maxRallyFrames = 60 * 120
+
+// Frame is one tick of a recorded rally
+type Frame struct {
+	LeftY  int     `json:"leftY"`
+	RightY int     `json:"rightY"`
+	BallX  float64 `json:"ballX"`
+	BallY  float64 `json:"ballY"`
+}
+
+// Highlight is the longest rally of the day along with its replay
+type Highlight struct {
+	Hits       int       `json:"hits"`
+	Day        string    `json:"day"`
+	RecordedAt time.Time `json:"recordedAt"`
+	Frames     []Frame   `json:"frames"`
+}
+
+// recordRallyFrame appends the current tick to the rally buffer. Caller must hold room lock.
+func (room *Room) recordRallyFrame() {
+	if len(room.rallyFrames) >= maxRallyFrames {
+		return
+	}
+	room.rallyFrames = append(room.rallyFrames, Frame{
+		LeftY:  room.PanYLeft,
+		RightY: room.PanYRight,
+		BallX:  room.Ball.X,
+		BallY:  room.Ball.Y,
+	})
+}
+
+// endRally offers the finished rally as the highlight and clears the buffer. Caller must hold room lock.
+func (room *Room) endRally() {
+	hits, frames := room.RallyHits, room.rallyFrames
+	room.RallyHits = 0
+	room.rallyFrames = nil
+	if hits == 0 {
+		return
+	}
+
+	now := time.Now()
+	day := now.Format("2006-01-02")
+
+	s := room.server
+	s.highlightMutex.Lock()
+	defer s.highlightMutex.Unlock()
+
+	// A new day starts a fresh record
+	if s.bestRally != nil && s.bestRally.Day == day && s.bestRally.Hits >= hits {
+		return
+	}
+	s.bestRally = &Highlight{Hits: hits, Day: day, RecordedAt: now, Frames: frames}
+	slog.Info("New rally of the day", "hits", hits)
+}
+
+// handleHighlights serves the best rally of the day as JSON
+func (s *Server) handleHighlights(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.highlightMutex.Lock()
+	best := s.bestRally
+	if best != nil && best.Day != time.Now().Format("2006-01-02") {
+		best = nil
+	}
+	s.highlightMutex.Unlock()
+
+	if best == nil {
+		http.Error(w, "no highlight recorded today", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(best); err != nil {
+		slog.Error("Error encoding highlight", "err", err)
+	}
+}
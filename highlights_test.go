@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLongerRallyReplacesHighlight(t *testing.T) {
+	s := NewServer(testConfig(t), nil)
+	room := s.NewSteppedRoom("highlights", RoomOptions{Mode: ClassicMode, Balls: 1, Rand: rand.New(rand.NewSource(1))})
+	rally := func(hits int) {
+		room.Lock()
+		defer room.Unlock()
+		room.RallyHits = hits
+		room.rallyFrames = make([]Frame, hits)
+		room.endRally()
+	}
+
+	rally(3)
+	if s.bestRally == nil || s.bestRally.Hits != 3 || len(s.bestRally.Frames) != 3 {
+		t.Fatalf("highlight after a 3 hit rally = %+v, want 3 hits", s.bestRally)
+	}
+	rally(7)
+	if s.bestRally.Hits != 7 || len(s.bestRally.Frames) != 7 {
+		t.Fatalf("highlight after a 7 hit rally has %d hits and %d frames, want 7 of each", s.bestRally.Hits, len(s.bestRally.Frames))
+	}
+	// A shorter rally leaves the record alone
+	rally(5)
+	if s.bestRally.Hits != 7 {
+		t.Fatalf("highlight after a 5 hit rally has %d hits, want the 7 hit record kept", s.bestRally.Hits)
+	}
+}
+
+func TestRallyRecordsOneFramePerTick(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	s := NewServer(testConfig(t), nil)
+	room := s.NewSteppedRoom("frames", RoomOptions{Mode: ClassicMode, Balls: 3, Rand: rand.New(rand.NewSource(1))})
+	frames := func() int {
+		room.Lock()
+		defer room.Unlock()
+		return len(room.rallyFrames)
+	}
+
+	for range 10000 {
+		if frames() > 0 {
+			break
+		}
+		room.Step()
+	}
+	if frames() == 0 {
+		t.Fatal("no rally frames recorded")
+	}
+	for range 10 {
+		before := frames()
+		room.Step()
+		if after := frames(); after != before+1 {
+			t.Fatalf("one tick with 3 balls took the rally from %d to %d frames, want one more", before, after)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Finished matches kept for /history; older ones are overwritten
+const maxHistory = 100
+
+// HistoryEntry is a finished match
+type HistoryEntry struct {
+	Room       string    `json:"room"`
+	Winner     string    `json:"winner"`
+	ScoreLeft  int       `json:"scoreLeft"`
+	ScoreRight int       `json:"scoreRight"`
+	DurationMs int64     `json:"durationMs"`
+	EndedAt    time.Time `json:"endedAt"`
+	Mode       string    `json:"mode,omitempty"`  // Set for survival runs
+	Rally      int       `json:"rally,omitempty"` // A survival run's length
+}
+
+// recordHistory adds a finished match to the history
+func (s *Server) recordHistory(roomID string, result MatchResult) {
+	entry := HistoryEntry{
+		Room:       roomID,
+		Winner:     result.Winner,
+		ScoreLeft:  result.ScoreLeft,
+		ScoreRight: result.ScoreRight,
+		DurationMs: result.Duration.Milliseconds(),
+		EndedAt:    time.Now(),
+		Mode:       result.Mode,
+		Rally:      result.Rally,
+	}
+
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	if len(s.history) < maxHistory {
+		s.history = append(s.history, entry)
+		return
+	}
+	s.history[s.historyNext] = entry
+	s.historyNext = (s.historyNext + 1) % maxHistory
+}
+
+// recentMatches returns the history, newest first
+func (s *Server) recentMatches() []HistoryEntry {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	matches := make([]HistoryEntry, 0, len(s.history))
+	for i := len(s.history) - 1; i >= 0; i-- {
+		matches = append(matches, s.history[(s.historyNext+i)%len(s.history)])
+	}
+	return matches
+}
+
+// handleHistory serves the recent match results as JSON, newest first
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.recentMatches()); err != nil {
+		slog.Error("Error encoding match history", "err", err)
+	}
+}
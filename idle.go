@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Room inactivity options. Activity is a paddle move, a serve or a point;
+// a room with no activity for the timeout is closed after a warning.
+var (
+	roomIdleTimeout = flag.Duration("room-idle-timeout", 0, "close the room after this long without moves or points (0 disables)")
+	roomIdleWarning = flag.Duration("room-idle-warning", 30*time.Second, "how long before an idle close to warn the players")
+)
+
+// markActivity resets the room's idle timer. Caller must hold room lock.
+func (room *Room) markActivity() {
+	room.lastActivity = room.now()
+	room.idleWarned = false
+}
+
+// checkIdle warns an idle room and closes it once the timeout passes
+func (room *Room) checkIdle(now time.Time) {
+	if *roomIdleTimeout <= 0 {
+		return
+	}
+
+	room.Lock()
+	if room.lastActivity.IsZero() {
+		room.lastActivity = now
+	}
+	idle := now.Sub(room.lastActivity)
+	remaining := *roomIdleTimeout - idle
+	warn := !room.idleWarned && remaining <= *roomIdleWarning && remaining > 0
+	if warn {
+		room.idleWarned = true
+	}
+	expired := remaining <= 0
+	if expired {
+		// Start fresh for whoever joins next
+		room.lastActivity = time.Time{}
+		room.idleWarned = false
+	}
+	room.Unlock()
+
+	switch {
+	case expired:
+		slog.Info("Room idle, closing", "room", room.ID, "idle", idle.Round(time.Second))
+		room.closeAllClients(websocket.CloseNormalClosure, "room idle")
+	case warn:
+		slog.Info("Room idle, warning players", "room", room.ID, "idle", idle.Round(time.Second))
+		msg := Message{
+			Type: IdleWarningMsg,
+			Hint: fmt.Sprintf("No activity; the room closes in %d seconds.", int(remaining.Seconds())),
+		}
+		room.server.stampEvent(&msg)
+		room.broadcast(msg)
+	}
+}
+
+// closeAllClients disconnects everyone with the given close code, after
+// whatever was already sent to them, queued connections included; their
+// handlers clean up their slots
+func (room *Room) closeAllClients(code int, reason string) {
+	room.queueMutex.Lock()
+	defer room.queueMutex.Unlock()
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+
+	for client := range room.clients {
+		room.hangUp(client, code, reason)
+	}
+	for client := range room.spectators {
+		room.hangUp(client, code, reason)
+	}
+	for _, entry := range room.waitQueue {
+		room.hangUp(entry.conn, code, reason)
+	}
+}
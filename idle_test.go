@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIdleRoomWarnedThenClosed(t *testing.T) {
+	setFlag(t, roomIdleTimeout, time.Minute)
+	setFlag(t, roomIdleWarning, 30*time.Second)
+	s, ts := newTestServer(t, testConfig(t))
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	// Frozen, the room's clock only moves when the test moves it
+	room := s.lookupRoom(DefaultRoomID)
+	room.Lock()
+	room.freeze()
+	room.markActivity()
+	start := room.lastActivity
+	room.Unlock()
+
+	room.checkIdle(start.Add(29 * time.Second))
+	room.Lock()
+	warned := room.idleWarned
+	room.Unlock()
+	if warned {
+		t.Fatal("warned 29s into a 1m idle timeout with a 30s warning")
+	}
+
+	room.checkIdle(start.Add(31 * time.Second))
+	if msg := readUntil(t, conn, IdleWarningMsg); msg.Hint == "" {
+		t.Fatal("idle warning has no hint")
+	}
+
+	room.checkIdle(start.Add(time.Minute))
+	for {
+		_, err := readMessage(conn)
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != "room idle" {
+				t.Fatalf("closed with %d %q, want %d \"room idle\"", closeErr.Code, closeErr.Text, websocket.CloseNormalClosure)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("connection ended with %v, want a close frame", err)
+		}
+	}
+}
+
+func TestActivityPostponesIdleClose(t *testing.T) {
+	setFlag(t, roomIdleTimeout, time.Minute)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	room.markActivity()
+	start := room.lastActivity
+	room.Unlock()
+
+	room.checkIdle(start.Add(50 * time.Second))
+	room.Lock()
+	room.simTime = start.Add(50 * time.Second)
+	room.markActivity()
+	room.Unlock()
+	room.checkIdle(start.Add(time.Minute))
+
+	room.Lock()
+	defer room.Unlock()
+	if room.idleWarned || !room.lastActivity.Equal(start.Add(50*time.Second)) {
+		t.Fatalf("room warned=%v with last activity %v after a move at +50s", room.idleWarned, room.lastActivity.Sub(start))
+	}
+}
+
+func TestIdleCloseReachesQueueAndClientsWithoutWriter(t *testing.T) {
+	setFlag(t, roomIdleTimeout, time.Minute)
+	setFlag(t, maxQueue, 1)
+	setFlag(t, maxConnsPerIP, 0)
+	s, ts := newTestServer(t, testConfig(t))
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+	queued := offeredQueue(t, ts)
+	if err := queued.WriteJSON(Message{Type: JoinMessage, Option: JoinQueue}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, queued, QueuedMessage)
+
+	room := s.lookupRoom(DefaultRoomID)
+	room.Lock()
+	room.freeze()
+	room.markActivity()
+	start := room.lastActivity
+	room.Unlock()
+
+	// A client whose writer isn't registered is closed outright
+	room.assignMutex.Lock()
+	var rightConn *websocket.Conn
+	for conn, side := range room.assignedPlayers {
+		if side == "right" {
+			rightConn = conn
+		}
+	}
+	room.assignMutex.Unlock()
+	room.removeSender(rightConn)
+
+	room.checkIdle(start.Add(time.Minute))
+	for name, conn := range map[string]*websocket.Conn{"player": left, "queued connection": queued} {
+		for {
+			_, err := readMessage(conn)
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				if closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != "room idle" {
+					t.Fatalf("%s closed with %d %q, want %d \"room idle\"", name, closeErr.Code, closeErr.Text, websocket.CloseNormalClosure)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s's connection ended with %v, want a close frame", name, err)
+			}
+		}
+	}
+	for {
+		if _, err := readMessage(right); err != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Input recording, for reproducing a desync. With -record-input set, every
+// frame a connection sends is appended, as received, to its own file,
+// <dir>/<room>-<player>-<remote addr>-<unix ms>.jsonl, one RecordedInput per
+// line. The file is buffered and flushed when the connection goes away.
+//
+// With -feed-input the server doesn't start; a recording is instead sent to
+// -feed-url at its original cadence, as if the client were playing again.
+var (
+	recordInput = flag.String("record-input", "", "record each connection's inbound frames to its own file in this directory (empty disables)")
+	feedInput   = flag.String("feed-input", "", "send this recorded input stream to -feed-url as a client, then exit")
+	feedURL     = flag.String("feed-url", "ws://localhost:8080/ws", "WebSocket URL -feed-input connects to, with any room or other parameters")
+)
+
+// RecordedInput is one line of an input recording
+type RecordedInput struct {
+	Offset int64  `json:"offset"` // Milliseconds since the connection joined
+	Frame  string `json:"frame"`  // As received, which may not be valid JSON
+}
+
+// inputRecording is the file one connection's input is recorded to
+type inputRecording struct {
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	started time.Time
+}
+
+// Characters of a remote address that don't belong in a file name
+var addrInFileName = strings.NewReplacer(":", "_", "[", "", "]", "")
+
+// startInputRecording opens the input recording of a connection that just
+// joined as player, or returns nil if input isn't being recorded
+func (room *Room) startInputRecording(ws *websocket.Conn, player string) *inputRecording {
+	if *recordInput == "" {
+		return nil
+	}
+	addr := ws.RemoteAddr().String()
+	name := filepath.Join(*recordInput, fmt.Sprintf("%s-%s-%s-%d.jsonl", room.ID, player, addrInFileName.Replace(addr), time.Now().UnixMilli()))
+	f, err := os.Create(name)
+	if err != nil {
+		slog.Error("Error starting input recording", "room", room.ID, "remote_addr", addr, "err", err)
+		return nil
+	}
+	slog.Info("Recording input", "room", room.ID, "remote_addr", addr, "player", player, "file", name)
+	w := bufio.NewWriter(f)
+	return &inputRecording{file: f, w: w, enc: json.NewEncoder(w), started: time.Now()}
+}
+
+// record appends a received frame. A nil recording records nothing.
+func (rec *inputRecording) record(frame []byte) {
+	if rec == nil {
+		return
+	}
+	if err := rec.enc.Encode(RecordedInput{Offset: time.Since(rec.started).Milliseconds(), Frame: string(frame)}); err != nil {
+		slog.Error("Error recording input", "file", rec.file.Name(), "err", err)
+	}
+}
+
+// close flushes and closes the recording
+func (rec *inputRecording) close() {
+	if rec == nil {
+		return
+	}
+	if err := rec.w.Flush(); err != nil {
+		slog.Error("Error flushing input recording", "file", rec.file.Name(), "err", err)
+	}
+	if err := rec.file.Close(); err != nil {
+		slog.Error("Error closing input recording", "file", rec.file.Name(), "err", err)
+	}
+}
+
+// loadInputRecording reads an input recording
+func loadInputRecording(path string) ([]RecordedInput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var inputs []RecordedInput
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var in RecordedInput
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		inputs = append(inputs, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s has no frames", path)
+	}
+	return inputs, nil
+}
+
+// feedRecordedInput joins the server at url and sends it a recorded input
+// stream, each frame as long after joining as it was first sent. What the
+// server sends back is read, so pings are answered, and logged at debug level.
+func feedRecordedInput(path, url string) error {
+	inputs, err := loadInputRecording(path)
+	if err != nil {
+		return err
+	}
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	slog.Info("Feeding recorded input", "file", path, "url", url, "frames", len(inputs))
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			slog.Debug("Received message", "message", string(data))
+		}
+	}()
+
+	start := time.Now()
+	for i, in := range inputs {
+		select {
+		case <-time.After(time.Until(start.Add(time.Duration(in.Offset) * time.Millisecond))):
+		case <-gone:
+			return fmt.Errorf("server closed the connection after %d of %d frames", i, len(inputs))
+		}
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(in.Frame)); err != nil {
+			return fmt.Errorf("frame %d: %w", i+1, err)
+		}
+	}
+	slog.Info("Recorded input sent", "frames", len(inputs))
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "input finished"))
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+	}
+	return nil
+}
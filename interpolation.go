@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// Snapshot timestamps for client-side interpolation.
+//
+// Every update and delta carries "t", the server's clock in milliseconds
+// since it started, read once per broadcast so all clients in a room share
+// one timeline. The clock is monotonic: it never goes backwards between
+// frames, even if the system clock is stepped, but it only means something
+// relative to other frames from the same server run.
+//
+// A client that renders smoothly between broadcasts keeps the last few
+// snapshots and draws the game as it was a little in the past, interpolating
+// between the two snapshots either side of that moment. Rendering about two
+// broadcast intervals behind the newest snapshot (broadcastMs in the
+// config sent on assign) leaves room for one late or lost frame; when the buffer runs dry
+// the client extrapolates from the last ball velocity until the next frame
+// arrives. The client's own paddle should not be delayed this way: it is
+// predicted from local input (see ack.go).
+//
+// Binary position frames carry no timestamp; binary clients should time
+// them on arrival, against the "t" of the last JSON frame.
+
+// When the server started, the zero of snapshot timestamps
+var serverStart = time.Now()
+
+// snapshotTime returns the timestamp for a snapshot taken at now. now must
+// come from time.Now so it keeps its monotonic clock reading.
+func snapshotTime(now time.Time) int64 {
+	return now.Sub(serverStart).Milliseconds()
+}
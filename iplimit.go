@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Connection limits per client address. Behind a reverse proxy every
+// connection comes from the proxy, so name the header it puts the client's
+// address in; its last entry is used, being the one the proxy added.
+var (
+	maxConnsPerIP      = flag.Int("max-conns-per-ip", 8, "most open WebSocket connections from one IP address (0 disables the limit)")
+	trustedProxyHeader = flag.String("trusted-proxy-header", "", "header a trusted reverse proxy sets to the client address, e.g. X-Forwarded-For (empty uses the connection's address)")
+)
+
+// clientIP returns the address a request is counted against
+func clientIP(r *http.Request) string {
+	if *trustedProxyHeader != "" {
+		if value := r.Header.Get(*trustedProxyHeader); value != "" {
+			entries := strings.Split(value, ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// claimIP counts a new connection from ip, reporting false without counting
+// it if the address is already at the limit
+func (s *Server) claimIP(ip string) bool {
+	s.connsMutex.Lock()
+	defer s.connsMutex.Unlock()
+
+	if *maxConnsPerIP > 0 && s.connsPerIP[ip] >= *maxConnsPerIP {
+		return false
+	}
+	s.connsPerIP[ip]++
+	return true
+}
+
+// releaseIP uncounts a connection claimed with claimIP
+func (s *Server) releaseIP(ip string) {
+	s.connsMutex.Lock()
+	defer s.connsMutex.Unlock()
+
+	s.connsPerIP[ip]--
+	if s.connsPerIP[ip] <= 0 {
+		delete(s.connsPerIP, ip)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name, header, forwarded, want string
+	}{
+		{"connection address", "", "", "10.0.0.1"},
+		{"header ignored unless trusted", "", "1.2.3.4", "10.0.0.1"},
+		{"trusted header", "X-Forwarded-For", "1.2.3.4", "1.2.3.4"},
+		{"last entry, added by the proxy", "X-Forwarded-For", "6.6.6.6, 1.2.3.4", "1.2.3.4"},
+		{"trusted header missing", "X-Forwarded-For", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		setFlag(t, trustedProxyHeader, tt.header)
+		r := &http.Request{RemoteAddr: "10.0.0.1:5555", Header: http.Header{}}
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionsPerIPLimited(t *testing.T) {
+	const limit = 3
+	setFlag(t, maxConnsPerIP, limit)
+	s, ts := newTestServer(t, testConfig(t))
+
+	conns := make([]*websocket.Conn, limit)
+	for i := range conns {
+		conns[i] = dialTest(t, ts, "")
+		readMessage(conns[i])
+	}
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(ts, ""), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("connection %d got %v, want a 429", limit+1, err)
+	}
+
+	// A connection closing makes room for another
+	conns[0].Close()
+	waitFor(t, "the connection to be uncounted", func() bool {
+		s.connsMutex.Lock()
+		defer s.connsMutex.Unlock()
+		return s.connsPerIP["127.0.0.1"] == limit-1
+	})
+	dialTest(t, ts, "")
+}
+
+func TestConnectionsPerIPByProxyHeader(t *testing.T) {
+	setFlag(t, maxConnsPerIP, 1)
+	setFlag(t, trustedProxyHeader, "X-Forwarded-For")
+	_, ts := newTestServer(t, testConfig(t))
+
+	dial := func(ip string) (*http.Response, error) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(ts, ""), http.Header{"X-Forwarded-For": {ip}})
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+		}
+		return resp, err
+	}
+	if _, err := dial("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial("2.2.2.2"); err != nil {
+		t.Fatalf("second client behind the proxy refused: %v", err)
+	}
+	if resp, err := dial("1.1.1.1"); err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second connection from 1.1.1.1 got %v, want a 429", err)
+	}
+}
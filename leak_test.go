@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestConnectionGoroutinesStop(t *testing.T) {
+	setFlag(t, maxConnsPerIP, 0)
+	s, ts := newTestServer(t, testConfig(t))
+	baseline := runtime.NumGoroutine()
+
+	for round := range 5 {
+		for i := range 10 {
+			query := fmt.Sprintf("?room=leak%d", i)
+			left := dialTest(t, ts, query)
+			readUntil(t, left, AssignMessage)
+			right := dialTest(t, ts, query)
+			readUntil(t, right, AssignMessage)
+			spectator := dialTest(t, ts, query)
+			readUntil(t, spectator, ErrorMessage)
+			if err := spectator.WriteJSON(Message{Type: JoinMessage, Option: JoinSpectate}); err != nil {
+				t.Fatal(err)
+			}
+			readUntil(t, spectator, AssignMessage)
+			left.Close()
+			right.Close()
+			spectator.Close()
+		}
+		waitFor(t, fmt.Sprintf("round %d's rooms to close", round), func() bool { return len(s.activeRooms()) == 0 })
+	}
+
+	// Every connection's reader, sender and keepalive goroutines are gone
+	waitFor(t, "the goroutines to stop", func() bool { return runtime.NumGoroutine() <= baseline })
+}
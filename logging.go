@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Logging options
+var (
+	logLevel  = flag.String("log-level", "info", "minimum log level: debug, info, warn or error")
+	logFormat = flag.String("log-format", "text", `log output format: "text" or "json"`)
+)
+
+// setupLogging installs the configured slog handler as the default logger.
+// The standard log package, still used for fatal startup errors, writes
+// through it too.
+func setupLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return fmt.Errorf("invalid -log-level %q", *logLevel)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch *logFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid -log-format %q", *logFormat)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
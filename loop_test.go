@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// logBuffer collects log output from several goroutines
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs sends the default logger's output to a buffer for the rest of the test
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+	logs := &logBuffer{}
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return logs
+}
+
+func TestGameLoopRecoversFromPanic(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	// Physics that blow up on their first tick only
+	var calls atomic.Int32
+	ballBehaviors["explode"] = func(*Ball) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+	}
+	t.Cleanup(func() { delete(ballBehaviors, "explode") })
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	s, ts := newTestServer(t, cfg)
+	logs := captureLogs(t)
+	conn := dialTest(t, ts, "?ai=easy")
+	readUntil(t, conn, AssignMessage)
+
+	room := s.lookupRoom(DefaultRoomID)
+	waitFor(t, "the match to start", func() bool {
+		room.Lock()
+		defer room.Unlock()
+		return room.State == StatePlaying
+	})
+	room.Lock()
+	room.Physics = "explode"
+	room.Unlock()
+
+	waitFor(t, "the loop to carry on past the panic", func() bool { return calls.Load() > 5 })
+	out := logs.String()
+	if !strings.Contains(out, "Recovered from panic in game loop") || !strings.Contains(out, "panic=boom") || !strings.Contains(out, "room="+DefaultRoomID) {
+		t.Fatalf("panic not logged with its room:\n%s", out)
+	}
+	if !strings.Contains(out, "Game state at panic") {
+		t.Fatalf("room state not logged after the panic:\n%s", out)
+	}
+}
+
+func TestNoBroadcastsWhileIdle(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	s, ts := newTestServer(t, cfg)
+
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	second := dialTest(t, ts, "")
+	readUntil(t, second, AssignMessage)
+	watcher := dialTest(t, ts, "")
+	if err := watcher.WriteJSON(Message{Type: JoinMessage, Option: "spectate"}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, watcher, UpdateMessage)
+
+	// With only a spectator left the room is idle
+	room := s.lookupRoom(DefaultRoomID)
+	first.Close()
+	second.Close()
+	waitFor(t, "the room to go idle", func() bool { return room.currentPhase() == PhaseIdle })
+	room.Lock()
+	sent := room.lastStateSent
+	room.Unlock()
+	time.Sleep(stateResendInterval + 100*time.Millisecond)
+	room.Lock()
+	resent := room.lastStateSent
+	room.Unlock()
+	if !resent.Equal(sent) {
+		t.Fatalf("idle room broadcast its state at %v", resent)
+	}
+
+	// A player coming back brings the broadcasts back
+	third := dialTest(t, ts, "")
+	readUntil(t, third, AssignMessage)
+	readUntil(t, watcher, UpdateMessage)
+	room.Lock()
+	resent = room.lastStateSent
+	room.Unlock()
+	if !resent.After(sent) {
+		t.Fatal("broadcasts didn't resume once a player joined")
+	}
+}
+
+func TestBroadcastToClosedClientsIsQuiet(t *testing.T) {
+	setFlag(t, maxSpectators, 20)
+	setFlag(t, maxConnsPerIP, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	s, ts := newTestServer(t, cfg)
+
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	second := dialTest(t, ts, "")
+	readUntil(t, second, AssignMessage)
+	room := s.lookupRoom(DefaultRoomID)
+	for range *maxSpectators {
+		conn := dialTest(t, ts, "")
+		if err := conn.WriteJSON(Message{Type: JoinMessage, Option: "spectate"}); err != nil {
+			t.Fatal(err)
+		}
+		readUntil(t, conn, UpdateMessage)
+	}
+	room.clientsMutex.Lock()
+	var watching []*websocket.Conn
+	for conn := range room.spectators {
+		watching = append(watching, conn)
+	}
+	room.clientsMutex.Unlock()
+	if len(watching) != *maxSpectators {
+		t.Fatalf("%d spectators joined, want %d", len(watching), *maxSpectators)
+	}
+
+	// Close the server's ends while broadcasts keep coming. Holding
+	// clientsMutex keeps the read loops from cleaning up until every
+	// writer has failed, so the broadcasts are the first to find out.
+	logs := captureLogs(t)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(5 * time.Millisecond):
+					room.broadcast(Message{Type: ChatMessage, Text: "hi"})
+				}
+			}
+		}()
+	}
+	room.clientsMutex.Lock()
+	for _, conn := range watching {
+		conn.Close()
+		room.sendTo(conn, websocket.TextMessage, []byte("{}"))
+	}
+	waitFor(t, "the writes to fail", func() bool {
+		for _, conn := range watching {
+			s := room.senders[conn]
+			s.mu.Lock()
+			failed := s.err != nil
+			s.mu.Unlock()
+			if !failed {
+				return false
+			}
+		}
+		return true
+	})
+	room.writeAll([]byte("{}"), "broadcasting", nil)
+	room.clientsMutex.Unlock()
+	waitFor(t, "the spectators to disconnect", func() bool {
+		return strings.Count(logs.String(), "player=spectator") == len(watching)
+	})
+	close(stop)
+	wg.Wait()
+
+	out := logs.String()
+	if strings.Contains(out, "Error writing to client") {
+		t.Fatalf("closed connections logged as write errors:\n%s", out)
+	}
+	for _, conn := range watching {
+		addr := "remote_addr=" + conn.RemoteAddr().String() + " "
+		if n := strings.Count(out, `msg="Client already closed" room=`+DefaultRoomID+" "+addr); n != 1 {
+			t.Errorf("%s dropped %d times, want once", conn.RemoteAddr(), n)
+		}
+	}
+}
@@ -1,59 +1,184 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
 	"net/http"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// Constants for canvas and paddle dimensions
+// Radius of the ball in pixels
+const BallRadius = 10
+
+// Keepalive timing. The server pings every PingInterval; a connection that
+// sends nothing (not even a pong) for PongWait is considered dead.
 const (
-	CanvasWidth  = 800
-	CanvasHeight = 600
-	PaddleHeight = 100
-	PaddleWidth  = 20
-	MaxPaddleY   = CanvasHeight - PaddleHeight
+	PingInterval = 30 * time.Second
+	PongWait     = 60 * time.Second
+	PingWait     = 10 * time.Second // Deadline for writing a ping
 )
 
+// Largest message a client may send, in bytes. Moves are a few dozen; the
+// biggest legitimate message is a chat line. A connection that sends more is
+// closed with 1009 (message too big) and cleaned up like any read error.
+const MaxMessageSize = 4096
+
 // Message types
 const (
-	AssignMessage = "assign"
-	MoveMessage   = "move"
-	UpdateMessage = "update"
-	GameOverMsg   = "gameover"
-	ErrorMessage  = "error"
+	AssignMessage   = "assign"
+	MoveMessage     = "move"
+	UpdateMessage   = "update"
+	GameOverMsg     = "gameover"
+	ErrorMessage    = "error"
+	VisibilityMsg   = "visibility"
+	SubscribeMsg    = "subscribe"
+	VoteMessage     = "vote"
+	VotesMessage    = "votes"
+	IdleWarningMsg  = "idle"
+	SettingsMessage = "settings"
+	QueuedMessage   = "queued"
+	AimMessage      = "aim"
+	ServeMessage    = "serve"
+	ShutdownMessage = "server_shutdown"
+	CountdownMsg    = "countdown"
+	PausedMsg       = "paused"
+	DeltaMsg        = "delta"
+	ChatMessage     = "chat"
+	EmoteMessage    = "emote"
+	IntermissionMsg = "intermission"
+	PresenceMsg     = "presence"
+	JoinMessage     = "join" // Choice of a client turned away from a full room
+	ColorMessage    = "color"
+	GameStartMsg    = "start"   // Play begins after the countdown
+	ResumedMsg      = "resumed" // Play goes on after an administrator paused it
+	PointMsg        = "point"   // A side scored; gameover still ends the match
+	AckMsg          = "ack"     // A player's own applied moves, see ack.go
 )
 
 // Message structure
 type Message struct {
-	Type   string  `json:"type"`
-	Player string  `json:"player,omitempty"`
-	Y      *int    `json:"y,omitempty"`
-	LeftY  int     `json:"leftY,omitempty"`
-	RightY int     `json:"rightY,omitempty"`
-	BallX  float64 `json:"ballX,omitempty"`
-	BallY  float64 `json:"ballY,omitempty"`
-	Winner string  `json:"winner,omitempty"` // For game over messages
+	Type           string            `json:"type"`
+	Player         string            `json:"player,omitempty"`
+	Y              *int              `json:"y,omitempty"`
+	X              *int              `json:"x,omitempty"`         // Flat paddle position, for top and bottom in quad mode
+	Direction      *int              `json:"direction,omitempty"` // Paddle velocity input: -1 up (left), 0 stop, 1 down (right)
+	LeftY          int               `json:"leftY,omitempty"`
+	RightY         int               `json:"rightY,omitempty"`
+	TopX           int               `json:"topX,omitempty"`
+	BottomX        int               `json:"bottomX,omitempty"`
+	BallX          float64           `json:"ballX,omitempty"`
+	BallY          float64           `json:"ballY,omitempty"`
+	Balls          []Vector          `json:"balls,omitempty"`          // Every ball, served one first, in multi-ball rooms
+	BallColor      string            `json:"ballColor,omitempty"`      // Set in color-bounce mode
+	Speed          float64           `json:"speed,omitempty"`          // Ball speed in pixels per tick
+	Level          int               `json:"level,omitempty"`          // Speed-ups so far this rally
+	Velocity       *Vector           `json:"velocity,omitempty"`       // Ball velocity in pixels per tick, on start, the first update and when it changes
+	Winner         string            `json:"winner,omitempty"`         // For game over messages
+	ScoreLeft      int               `json:"scoreLeft,omitempty"`      // Left side points
+	ScoreRight     int               `json:"scoreRight,omitempty"`     // Right side points
+	Series         *SeriesScore      `json:"series,omitempty"`         // Series standing, after a game of a series
+	Eliminated     []string          `json:"eliminated,omitempty"`     // Sides out of the quad match
+	Names          *SideNames        `json:"names,omitempty"`          // Display names for the sides
+	Presence       *Presence         `json:"presence,omitempty"`       // Who is connected, on assign and presence messages
+	Config         *Config           `json:"config,omitempty"`         // Board geometry, sent on assign
+	Token          string            `json:"token,omitempty"`          // Reconnection token, sent on assign
+	Name           string            `json:"name,omitempty"`           // Player name, echoed on assign
+	Mode           string            `json:"mode,omitempty"`           // Game mode, sent on assign
+	Visibility     string            `json:"visibility,omitempty"`     // "foreground" or "background"
+	Paused         bool              `json:"paused,omitempty"`         // Play is halted
+	Subscription   string            `json:"subscription,omitempty"`   // "full" or "score"
+	Option         string            `json:"option,omitempty"`         // Vote choice
+	Text           string            `json:"text,omitempty"`           // Chat message
+	Emote          string            `json:"emote,omitempty"`          // Predefined emote
+	Rally          *int              `json:"rally,omitempty"`          // Returns in the survival run in progress, or the one that just ended
+	BestRally      int               `json:"bestRally,omitempty"`      // The survival player's longest run in the room
+	Color          string            `json:"color,omitempty"`          // Paddle color a player picks
+	Tally          map[string]int    `json:"tally,omitempty"`          // Vote counts per option
+	Physics        string            `json:"physics,omitempty"`        // Active ball physics
+	PendingPhysics string            `json:"pendingPhysics,omitempty"` // Physics from the next serve
+	Host           string            `json:"host,omitempty"`           // Player who can change settings
+	State          string            `json:"state,omitempty"`          // Match state
+	Position       int               `json:"position,omitempty"`       // Place in the waiting queue
+	RetryAfter     int               `json:"retryAfter,omitempty"`     // Seconds to wait before reconnecting, on room_full and queue_full errors
+	Options        []string          `json:"options,omitempty"`        // What a client turned away from a full room may do instead
+	Count          int               `json:"count,omitempty"`          // Seconds left before the match starts
+	Code           string            `json:"code,omitempty"`           // Error code for error messages
+	Hint           string            `json:"hint,omitempty"`           // Human readable hint for error messages
+	Effects        []Effect          `json:"effects,omitempty"`        // Active paddle effects
+	Band           *Band             `json:"band,omitempty"`           // Scoring band on the back walls
+	Portals        []PortalPair      `json:"portals,omitempty"`        // Portal pairs on the court
+	PowerUps       []PowerUp         `json:"powerUps,omitempty"`       // Power-ups on the court
+	Lengths        map[string]int    `json:"lengths,omitempty"`        // Paddle lengths power-ups changed, by side
+	Wind           *Vector           `json:"wind,omitempty"`           // Wind force on the ball
+	Serving        string            `json:"serving,omitempty"`        // Player holding the serve
+	Angle          *float64          `json:"angle,omitempty"`          // Serve angle in degrees
+	Reach          map[string]Range  `json:"reach,omitempty"`          // Restricted paddle ranges by side
+	Seq            uint64            `json:"seq,omitempty"`            // Event sequence number
+	InputSeq       uint64            `json:"inputSeq,omitempty"`       // Client's input sequence, on moves
+	Acks           map[string]uint64 `json:"acks,omitempty"`           // Receiver's last applied move inputSeq by side, on acks
+	Time           int64             `json:"time,omitempty"`           // Server timestamp in Unix milliseconds
+	T              int64             `json:"t,omitempty"`              // Server clock on updates, in milliseconds since the server started
+	TimeLeft       *int              `json:"timeLeft,omitempty"`       // Seconds left on the match clock, in timed matches
+	SuddenDeath    bool              `json:"suddenDeath,omitempty"`    // Time ran out level; the next point wins
+}
+
+// Vector is a 2D quantity such as a force
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
+// Error codes. Clients can branch on them; the hint is for people.
+const (
+	ClientOutdatedError = "client_outdated"
+	QueueFullError      = "queue_full"    // Chose the queue, but it filled up; see retryAfter
+	RoomFullError       = "room_full"     // No paddle free; see retryAfter, and options and position if it may wait
+	MalformedError      = "malformed"     // The frame doesn't decode as a message
+	UnknownTypeError    = "unknown_type"  // Message type isn't one clients send
+	MissingFieldError   = "missing_field" // A field the message type needs is absent
+	OutOfRangeError     = "out_of_range"  // A field's value isn't allowed
+	ModeMismatchError   = "mode_mismatch" // The room exists in another game mode
+)
+
+// Close codes the server ends connections with on purpose, beyond the
+// standard ones (1000 normal, 1001 shutting down, 1008 policy violation,
+// 1009 message too big, 1011 server error). 4000-4999 are left to
+// applications. Anything else, or no close frame at all, means the
+// connection was lost.
+const (
+	CloseRoomFull       = 4001 // No paddle free, and the client didn't queue or watch
+	CloseQueueFull      = 4002
+	CloseClientOutdated = 4003
+	CloseModeMismatch   = 4004
+	CloseReplaced       = 4005 // The player reconnected from elsewhere; don't reconnect
+)
+
 // Ball structure representing the ball's state
 type Ball struct {
 	X  float64 `json:"x"`
 	Y  float64 `json:"y"`
 	Vx float64 `json:"vx"`
 	Vy float64 `json:"vy"`
-}
-
-// Define the upgrader
-var upgrader = websocket.Upgrader{
-	// Allow all origins for simplicity. In production, restrict this.
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	// Color assigned on the last bounce in color-bounce mode
+	Color string `json:"color,omitempty"`
+	// Portal the ball just came out of; it can't re-enter until it leaves
+	portalExit *Portal
 }
 
 // Paddle positions
@@ -65,328 +190,1666 @@ type PaddlePositions struct {
 // Game state structure
 type GameState struct {
 	sync.Mutex
-	PanYLeft  int
-	PanYRight int
-	Ball      Ball
+	// Game mode and whether power-ups appear, set when the room is created
+	Mode            string
+	PowerUpsEnabled bool
+	// Display names for the sides, set when the room is created
+	Names SideNames
+	// Balls in play besides Ball, in multi-ball rooms; Ball is the one served
+	ExtraBalls []Ball
+	PanYLeft   int
+	PanYRight  int
+	// Flat paddles' left edges, in quad mode
+	PanXTop    int
+	PanXBottom int
+	// Sides knocked out of the quad match in progress, or that nobody plays
+	Eliminated []string
+	Ball       Ball
+	Effects    []Effect
+	Portals    []PortalPair
+	// Points in the current match
+	ScoreLeft  int
+	ScoreRight int
+	// Paddle hits in the current rally
+	RallyHits int
+	// Paddle hits counted toward the next speed-up, and speed-ups so far, in
+	// the current rally
+	speedHits int
+	SpeedTier int
+	// Returns in the survival run in progress, the player's longest run in
+	// the room, and when the ball next speeds up
+	Rally     int
+	BestRally int
+	rampAt    time.Time
+	// Ticks recorded since the last serve, for highlights
+	rallyFrames []Frame
+	// Players whose tab is currently in the background
+	Background map[string]bool
+	// Constant force applied to the ball every tick
+	Wind Vector
+	// Player holding the serve in aimed-serve mode, empty while the ball is in play
+	Serving string
+	// Pending serve angle in degrees
+	ServeAngle float64
+	// Serve speed on each axis, chosen by vote between matches
+	BallSpeed float64
+	// Player allowed to change match settings: the first to join
+	Host string
+	// Active ball physics, and the one queued for the next serve
+	Physics        string
+	PendingPhysics string
+	// Last move, serve or point, for the room idle timeout
+	lastActivity time.Time
+	idleWarned   bool
+	// Per-player comfort ranges restricting where their paddle may go
+	Reach map[string]Range
+	// Board geometry and timing
+	Config Config
+	// Match state, and when the countdown to the first serve ends
+	State          string
+	countdownEnds  time.Time
+	countdownShown int // Last second announced
+	// Points scored in the physics tick in progress, announced by tick once
+	// the room lock is released
+	scoredPoints []Message
+	matchStarted time.Time // First serve of the match in progress
+	// Named players by side, and as they were when the match started
+	PlayerNames  map[string]string
+	matchPlayers map[string]string
+	// Paddle colors players picked, by side
+	Colors map[string]string
+	// Games won in the series in progress by the player on each side
+	SeriesWins   map[string]int
+	seriesPlayed int
+	// When each player's last absolute move was applied, for limitJump
+	lastMoveAt map[string]time.Time
+	// Paddles moving under velocity input, by player, and the fraction of a
+	// pixel each has yet to move
+	Directions  map[string]int
+	paddleCarry map[string]float64
+	// Side that dropped from a paused match, and when it forfeits
+	Missing   string
+	graceEnds time.Time
+	// Last applied move seq by player, and the last each was told of
+	Acks     map[string]uint64
+	sentAcks map[string]uint64
+	// Power-ups on the court, when the next appears, and who can collect them
+	PowerUps    []PowerUp
+	nextPowerUp time.Time
+	lastHitter  string
+	// Pixels each upright paddle moved last tick, and where they were, for spin
+	PaddleVelocity map[string]int
+	lastPaddleY    map[string]int
+	// Side that lost the last point and receives the serve, and when a
+	// delayed serve leaves (zero once it has)
+	receiver string
+	serveAt  time.Time
+	// Play counted against the time limit, and whether it ran out level
+	clockUsed   time.Duration
+	SuddenDeath bool
 }
 
-// Initialize game state
-var gameState = GameState{
-	PanYLeft:  CanvasHeight/2 - PaddleHeight/2, // 250
-	PanYRight: CanvasHeight/2 - PaddleHeight/2, // 250
-	Ball: Ball{
-		X:  float64(CanvasWidth / 2),
-		Y:  float64(CanvasHeight / 2),
-		Vx: 4.0, // Horizontal velocity
-		Vy: 4.0, // Vertical velocity
-	},
-}
+// Subscription levels a client can request
+const (
+	FullSubscription  = "full"  // Every tick plus events
+	ScoreSubscription = "score" // Score and match events only, no per-tick positions
+)
 
-var clients = make(map[*websocket.Conn]string)
-var clientsMutex = sync.Mutex{}
+// Longest the game state goes unsent while nothing changes
+const stateResendInterval = time.Second
 
-// Tick rate (60 FPS)
-var ticker = time.NewTicker(time.Millisecond * 16) // Approximately 60 FPS
+// stateMessage is a full update of the room as it stands, without the ball's
+// velocity or the timestamp, which broadcasts and a joining client's first
+// frame fill in differently. Nothing in it is shared with the room, so it may
+// be sent after the lock is released. Caller must hold room lock.
+func (room *Room) stateMessage() Message {
+	// Effects run on the room's clock, which stops while it is frozen
+	clock := room.now()
+	return Message{
+		Type:           UpdateMessage,
+		LeftY:          room.PanYLeft,
+		RightY:         room.PanYRight,
+		TopX:           room.PanXTop,
+		BottomX:        room.PanXBottom,
+		Eliminated:     slices.Clone(room.Eliminated),
+		BallX:          room.Ball.X,
+		BallY:          room.Ball.Y,
+		Balls:          room.ballPositions(),
+		Effects:        room.activeEffects(clock),
+		Band:           scoringBandBounds(room.Config),
+		Portals:        room.Portals,
+		PowerUps:       slices.Clone(room.PowerUps),
+		Lengths:        room.resizedPaddles(),
+		Paused:         room.pausedForBackground() || room.frozen,
+		BallColor:      room.Ball.Color,
+		Speed:          math.Hypot(room.Ball.Vx, room.Ball.Vy),
+		Level:          room.SpeedTier,
+		Rally:          room.survivalRally(),
+		BestRally:      room.BestRally,
+		Wind:           room.windVector(),
+		Serving:        room.Serving,
+		Angle:          room.serveAngle(),
+		Reach:          maps.Clone(room.Reach),
+		Physics:        room.Physics,
+		PendingPhysics: room.PendingPhysics,
+		Host:           room.Host,
+		State:          room.State,
+		Names:          room.sideNames(),
+		ScoreLeft:      room.ScoreLeft,
+		ScoreRight:     room.ScoreRight,
+		TimeLeft:       room.timeLeft(),
+		SuddenDeath:    room.SuddenDeath,
+	}
+}
 
 // Broadcast function to send game state to all clients
-func broadcastGameState() {
-	gameState.Lock()
-	defer gameState.Unlock()
+func (room *Room) broadcastGameState() {
+	room.Lock()
+	defer room.Unlock()
 
-	msg := Message{
-		Type:   UpdateMessage,
-		LeftY:  gameState.PanYLeft,
-		RightY: gameState.PanYRight,
-		BallX:  gameState.Ball.X,
-		BallY:  gameState.Ball.Y,
+	// The wall clock read once for the whole frame, for its timestamp
+	now := time.Now()
+	msg := room.stateMessage()
+	msg.Velocity = room.velocityUpdate()
+
+	stateBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Error marshaling game state", "room", room.ID, "err", err)
+		return
 	}
 
+	// Nothing moved; new clients get their first frame from the handler, so
+	// only resend now and then to show the game is still alive
+	if bytes.Equal(stateBytes, room.lastState) && now.Sub(room.lastStateSent) < stateResendInterval {
+		// A move that changed nothing is still shown by the last frame
+		room.clientsMutex.Lock()
+		room.sendAcks()
+		room.clientsMutex.Unlock()
+		return
+	}
+	room.lastState = stateBytes
+	room.lastStateSent = now
+
+	// Stamped only now so the timestamp alone never counts as a change
+	msg.T = snapshotTime(now)
 	msgBytes, err := json.Marshal(msg)
 	if err != nil {
-		log.Println("Error marshaling game state:", err)
+		slog.Error("Error marshaling game state", "room", room.ID, "err", err)
 		return
 	}
 
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
+	frame, delta, err := room.encodeUpdate(msg, msgBytes)
+	if err != nil {
+		slog.Error("Error marshaling game state delta", "room", room.ID, "err", err)
+		return
+	}
 
-	for client := range clients {
-		err := client.WriteMessage(websocket.TextMessage, msgBytes)
-		if err != nil {
-			log.Println("Error broadcasting to client:", err)
-			client.Close()
-			delete(clients, client)
-		}
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	defer room.sendAcks()
+
+	// Score-only subscribers don't get per-tick positions
+	scoreOnly := func(client *websocket.Conn) bool {
+		return room.subscriptions[client] == ScoreSubscription
+	}
+	room.writeLegacy(msg, "broadcasting", scoreOnly)
+	if !delta {
+		room.writeAll(frame, "broadcasting", scoreOnly)
+		return
 	}
+	// Binary clients get their positions packed instead of the JSON delta
+	room.writeAll(frame, "broadcasting", func(client *websocket.Conn) bool {
+		_, isBinary := room.binaryClients[client]
+		return isBinary || scoreOnly(client)
+	})
+	room.writeBinary(encodePositions(Positions{
+		LeftY:  msg.LeftY,
+		RightY: msg.RightY,
+		BallX:  msg.BallX,
+		BallY:  msg.BallY,
+		Speed:  msg.Speed,
+	}), "broadcasting", scoreOnly)
 }
 
-// Broadcast game over message
-func broadcastGameOver(winner string) {
-	gameState.Lock()
-	defer gameState.Unlock()
+// isClosedConnError reports whether err just means the connection was already
+// closed, by the peer or by another goroutine, as opposed to a real failure
+func isClosedConnError(err error) bool {
+	return errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, websocket.ErrCloseSent) ||
+		websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived)
+}
 
-	msg := Message{
-		Type:   GameOverMsg,
-		Winner: winner,
+// dropClient removes a client after a failed or refused write. Dropping a client that is
+// already gone is a no-op, and writes that failed only because the connection
+// had been closed elsewhere aren't logged as errors. Caller must hold clientsMutex.
+func (room *Room) dropClient(client *websocket.Conn, err error, action string) {
+	droppedCounter.Inc()
+	_, isPlayer := room.clients[client]
+	_, isSpectator := room.spectators[client]
+	if !isPlayer && !isSpectator {
+		return
+	}
+	switch {
+	case errors.Is(err, errSenderClosed):
+		// Closing on purpose; its writer closes it once the close frame is out
+		slog.Debug("Client closing", "room", room.ID, "remote_addr", client.RemoteAddr().String(), "action", action)
+	case isClosedConnError(err):
+		slog.Debug("Client already closed", "room", room.ID, "remote_addr", client.RemoteAddr().String(), "action", action)
+		client.Close()
+	default:
+		slog.Warn("Error writing to client", "room", room.ID, "remote_addr", client.RemoteAddr().String(), "action", action, "err", err)
+		client.Close()
 	}
+	delete(room.clients, client)
+	delete(room.spectators, client)
+	delete(room.subscriptions, client)
+	delete(room.binaryClients, client)
+}
 
+// broadcast sends a message to every client
+func (room *Room) broadcast(msg Message) {
 	msgBytes, err := json.Marshal(msg)
 	if err != nil {
-		log.Println("Error marshaling game over message:", err)
+		slog.Error("Error marshaling message", "room", room.ID, "msg_type", msg.Type, "err", err)
 		return
 	}
 
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
 
-	for client := range clients {
-		err := client.WriteMessage(websocket.TextMessage, msgBytes)
-		if err != nil {
-			log.Println("Error broadcasting game over to client:", err)
-			client.Close()
-			delete(clients, client)
-		}
-	}
+	room.writeAll(msgBytes, "broadcasting "+msg.Type, nil)
+	room.writeLegacy(msg, "broadcasting "+msg.Type, nil)
 }
 
-// Assign players to paddles
-var assignedPlayers = make(map[*websocket.Conn]string)
-var assignMutex = sync.Mutex{}
+// Broadcast game over message. Must be called without holding room lock.
+func (room *Room) broadcastGameOver(result MatchResult) {
+	msg := Message{
+		Type:       GameOverMsg,
+		Winner:     result.Winner,
+		ScoreLeft:  result.ScoreLeft,
+		ScoreRight: result.ScoreRight,
+		Series:     result.Series,
+		Names:      &result.Names,
+		Rally:      result.survivalRally(),
+		BestRally:  result.BestRally,
+	}
+	room.server.stampEvent(&msg)
+	room.emitEvent(Event{Type: GameOverEvent, Winner: result.Winner})
+	matchesCounter.Inc()
+	room.server.recordHistory(room.ID, result)
+	if result.Mode == SurvivalMode {
+		room.server.recordSurvival(result)
+	} else {
+		room.server.recordStats(room.ID, result)
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Error marshaling message", "room", room.ID, "msg_type", msg.Type, "err", err)
+		return
+	}
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	room.writeAll(msgBytes, "broadcasting "+msg.Type, nil)
+	room.writeLegacy(msg, "broadcasting "+msg.Type, nil)
+	// Closed before letting go of the clients, so no frame sent after the
+	// gameover lands in the match's recording
+	room.closeRecording()
+}
 
-// Assign a player to a paddle
-func assignPlayer(conn *websocket.Conn) (string, error) {
-	assignMutex.Lock()
-	defer assignMutex.Unlock()
+// Assign a player to a paddle. A token from an earlier connection gets that
+// connection's paddle back.
+func (room *Room) assignPlayer(conn *websocket.Conn, token string) (string, error) {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
 
 	// Check current assignments
-	roles := map[string]bool{"left": false, "right": false}
-	for _, role := range assignedPlayers {
-		if role == "left" {
-			roles["left"] = true
-		}
-		if role == "right" {
-			roles["right"] = true
-		}
+	roles := make(map[string]bool)
+	for _, role := range room.assignedPlayers {
+		roles[role] = true
 	}
 
-	var assigned string
-	if !roles["left"] {
-		assigned = "left"
-	} else if !roles["right"] {
-		assigned = "right"
+	// A held paddle is kept for the player who dropped it
+	if room.heldSide != "" {
+		roles[room.heldSide] = true
+	}
+
+	sides := room.sides()
+	switch room.Mode {
+	case SurvivalMode:
+		// One player; the AI has the other paddle
+		sides = sides[:1]
+	case PracticeMode:
+		// One player, facing the wall
+		sides = []string{room.survivor()}
+	}
+
+	assigned := "none" // Every paddle taken
+	if side := room.reclaimSide(token); side != "" {
+		assigned = side
 	} else {
-		assigned = "none" // Max two players
+		for _, side := range sides {
+			if !roles[side] {
+				assigned = side
+				break
+			}
+		}
 	}
 
 	if assigned != "none" {
-		assignedPlayers[conn] = assigned
-		log.Printf("Assigned player %s to %s paddle", conn.RemoteAddr(), assigned)
+		room.assign(conn, assigned)
+		if err := room.checkSoleHolder(conn); err != nil {
+			return "", err
+		}
+		slog.Info("Assigned paddle", "room", room.ID, "remote_addr", conn.RemoteAddr().String(), "player", assigned)
+		if assigned == room.heldSide {
+			room.heldSide = ""
+		}
+		if token == "" || room.tokens[assigned] != token {
+			room.tokens[assigned] = newToken()
+		}
 	} else {
-		log.Printf("No available paddle for player %s", conn.RemoteAddr())
+		slog.Info("No available paddle", "room", room.ID, "remote_addr", conn.RemoteAddr().String())
 	}
 
 	return assigned, nil
 }
 
-// Clamp Y position within bounds
-func clampYPosition(y int) int {
-	if y < 0 {
-		return 0
-	}
-	if y > MaxPaddleY {
-		return MaxPaddleY
+// ballHitsPaddle reports whether a ball overlaps a side's upright paddle,
+// whose left edge is at x. Caller must hold room lock.
+func (room *Room) ballHitsPaddle(ball *Ball, side string, x float64) bool {
+	top, length := room.paddleSpan(side)
+	return ballHitsRect(ball, x, top, float64(room.Config.PaddleWidth), length)
+}
+
+// ballHitsRect reports whether a ball overlaps the w by h rectangle whose
+// top-left corner is at (x, y). The ball is treated as a circle, so near the
+// corners it is the distance from the ball's center to the corner that
+// decides. A ball exactly touching the rectangle (distance equal to the
+// radius) counts as a hit.
+func ballHitsRect(ball *Ball, x, y, w, h float64) bool {
+	// Closest point on the rectangle to the ball's center
+	cx := math.Max(x, math.Min(ball.X, x+w))
+	cy := math.Max(y, math.Min(ball.Y, y+h))
+	dx, dy := ball.X-cx, ball.Y-cy
+	return dx*dx+dy*dy <= BallRadius*BallRadius
+}
+
+// opponent returns the other side of the court
+func opponent(player string) string {
+	if player == "left" {
+		return "right"
 	}
-	return y
+	return "left"
 }
 
-// Handle incoming WebSocket connections
-func handleConnections(w http.ResponseWriter, r *http.Request) {
+// HandleConnections handles incoming WebSocket connections
+func (s *Server) HandleConnections(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Query().Get("room")
+	if roomID == "" {
+		roomID = DefaultRoomID
+	}
+	if err := validateRoomID(roomID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	proto := r.URL.Query().Get("proto")
+	if proto != "" && proto != JSONProtocol && proto != BinaryProtocol {
+		http.Error(w, "unknown proto "+proto, http.StatusBadRequest)
+		return
+	}
+	name, err := resolvePlayerName(r.URL.Query().Get("name"))
+	if errors.Is(err, errNoToken) {
+		slog.Error("Error making up a guest name", "remote_addr", r.RemoteAddr, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	soloAI := r.URL.Query().Get("ai")
+	if _, ok := aiLevels[soloAI]; soloAI != "" && !ok {
+		http.Error(w, "unknown ai level "+soloAI, http.StatusBadRequest)
+		return
+	}
+	// Without a mode the client joins the room whatever its mode
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != ClassicMode && mode != QuadMode && mode != SurvivalMode && mode != PracticeMode {
+		http.Error(w, "unknown mode "+mode, http.StatusBadRequest)
+		return
+	}
+	balls, err := parseBalls(r.URL.Query().Get("balls"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Only used if this connection creates the room
+	names, err := parseSideNames(cmp.Or(r.URL.Query().Get("left-name"), *leftName), cmp.Or(r.URL.Query().Get("right-name"), *rightName))
+	if err != nil {
+		http.Error(w, "side name "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	color := r.URL.Query().Get("color")
+	if color != "" {
+		if color, err = validateColor(color); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !supportedProtocol(r) {
+		http.Error(w, "unsupported protocol version; this server speaks "+strings.Join(protocols, ", "), http.StatusBadRequest)
+		return
+	}
+
+	// Refuse before upgrading so an address at its limit costs us nothing
+	ip := clientIP(r)
+	if !s.claimIP(ip) {
+		slog.Warn("Too many connections from one address", "remote_addr", r.RemoteAddr, "ip", ip, "limit", *maxConnsPerIP)
+		rejectedCounter.Inc()
+		http.Error(w, "too many connections from your address", http.StatusTooManyRequests)
+		return
+	}
+	defer s.releaseIP(ip)
+
 	// Upgrade initial GET request to a WebSocket
-	ws, err := upgrader.Upgrade(w, r, nil)
+	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("Upgrade error:", err)
+		slog.Warn("Upgrade error", "remote_addr", r.RemoteAddr, "err", err)
 		return
 	}
 	defer ws.Close()
+	ws.SetReadLimit(MaxMessageSize)
+	connectionsGauge.Inc()
+	defer connectionsGauge.Dec()
+
+	// Every goroutine serving the connection stops once ctx is done: when
+	// this handler returns, or when the server gives up on a graceful
+	// shutdown. Closing the connection then ends the read loop, whose
+	// cleanup runs as usual.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(s.ctx, cancel)()
+	context.AfterFunc(ctx, func() { ws.Close() })
+
+	// Everything but control frames goes out through the connection's writer
+	out := newSender(ctx, ws)
+	defer out.stop()
+
+	room := s.acquireRoom(roomID, RoomOptions{
+		Mode:     cmp.Or(mode, ClassicMode),
+		PowerUps: r.URL.Query().Get("powerups") == "true",
+		Balls:    balls,
+		Names:    names,
+	})
+	defer s.releaseRoom(room)
+	room.addSender(ws, out)
+	defer room.removeSender(ws)
+
+	if mode != "" && room.Mode != mode {
+		slog.Info("Rejecting connection for another mode", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "mode", mode, "room_mode", room.Mode)
+		out.sendJSON(Message{
+			Type: ErrorMessage,
+			Code: ModeMismatchError,
+			Hint: "Room " + room.ID + " is already playing " + room.Mode + " mode.",
+		})
+		out.closeWith(CloseModeMismatch, "room is in another mode")
+		return
+	}
+
+	if err := ws.SetCompressionLevel(*compressionLevel); err != nil {
+		slog.Error("Error setting compression level", "err", err)
+	}
+
+	// Reject clients older than the configured minimum
+	if err := checkClientVersion(r.URL.Query().Get("version")); err != nil {
+		slog.Info("Rejecting outdated client", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		out.sendJSON(Message{
+			Type: ErrorMessage,
+			Code: ClientOutdatedError,
+			Hint: "Please reload the page to get the latest version (minimum " + *minClientVersion + ").",
+		})
+		out.closeWith(CloseClientOutdated, "client outdated")
+		return
+	}
 
 	// Assign player
-	player, err := assignPlayer(ws)
+	player, err := room.assignPlayer(ws, r.URL.Query().Get("token"))
 	if err != nil {
-		log.Println("Player assignment error:", err)
+		slog.Error("Player assignment error", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		out.closeWith(websocket.CloseInternalServerErr, "assignment failed")
 		return
 	}
 
+	// Both paddles taken; the client may queue or watch instead
+	var queued *queueReader
 	if player == "none" {
-		// Inform client no slot available
-		msg := Message{
-			Type:   ErrorMessage,
-			Player: "none",
+		player, queued = room.handleRoomFull(ctx, ws, out)
+		if player == "" {
+			return
 		}
-		ws.WriteJSON(msg)
-		return
 	}
 
-	// Add to clients
-	clientsMutex.Lock()
-	clients[ws] = player
-	clientsMutex.Unlock()
+	if player != SpectatorRole {
+		// Optional comfort range for this player's paddle
+		reach, err := parseReach(r.URL.Query(), room.Config.MaxPaddleY())
+		if err != nil {
+			slog.Warn("Ignoring reach", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		}
+		if horizontal(player) {
+			reach = nil // Ranges are along Y
+		}
+		room.Lock()
+		room.setReach(player, reach)
+		room.setPlayerName(player, name)
+		room.setColor(player, color)
+		room.markActivity()
+		if room.Host == "" {
+			room.Host = player
+		}
+		if room.Mode == SurvivalMode {
+			room.soloAI = cmp.Or(soloAI, survivalAI)
+		} else if soloAI != "" && (room.Mode == QuadMode || room.Mode == PracticeMode) {
+			slog.Info("Ignoring AI request", "room", room.ID, "mode", room.Mode, "ai", soloAI)
+		} else if soloAI != "" {
+			room.soloAI = soloAI
+			slog.Info("AI plays the free paddle until a second player joins", "room", room.ID, "ai", soloAI)
+		}
+		room.Unlock()
+
+		// Add to clients
+		room.clientsMutex.Lock()
+		room.clients[ws] = player
+		room.clientsMutex.Unlock()
+	}
+
+	// Binary frames only carry the classic paddles and one ball
+	room.Lock()
+	packable := room.Mode == ClassicMode && len(room.ExtraBalls) == 0
+	room.Unlock()
+	if proto == BinaryProtocol && packable && ws.Subprotocol() != ProtocolV1 {
+		room.clientsMutex.Lock()
+		room.binaryClients[ws] = struct{}{}
+		room.clientsMutex.Unlock()
+	}
 
 	// Send assign message
 	assignMsg := Message{
-		Type:   AssignMessage,
-		Player: player,
+		Type:     AssignMessage,
+		Player:   player,
+		Config:   &room.Config,
+		Token:    room.paddleToken(player),
+		Name:     name,
+		Mode:     room.Mode,
+		Presence: room.presence(),
 	}
-	if err := ws.WriteJSON(assignMsg); err != nil {
-		log.Println("Error sending assign message:", err)
+	if err := out.sendJSON(assignMsg); err != nil {
+		slog.Warn("Error sending assign message", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		room.abandonJoin(ws)
+		return
 	}
+	room.recordMessage(assignMsg)
 
-	// Send initial game state
-	gameState.Lock()
-	initialMsg := Message{
-		Type:   UpdateMessage,
-		LeftY:  gameState.PanYLeft,
-		RightY: gameState.PanYRight,
-		BallX:  gameState.Ball.X,
-		BallY:  gameState.Ball.Y,
+	// Send initial game state, the same full update a broadcast would carry.
+	// The next broadcast is full too: a delta against a frame this client
+	// never got would leave it drawing the wrong board until the next resync.
+	room.Lock()
+	initialMsg := room.stateMessage()
+	initialMsg.Velocity = room.ballVelocity()
+	initialMsg.T = snapshotTime(time.Now())
+	room.lastRest = nil
+	room.Unlock()
+	if err := out.sendJSON(initialMsg); err != nil {
+		slog.Warn("Error sending initial game state", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		room.abandonJoin(ws)
+		return
 	}
-	gameState.Unlock()
-	if err := ws.WriteJSON(initialMsg); err != nil {
-		log.Println("Error sending initial game state:", err)
+	room.recordMessage(initialMsg)
+
+	slog.Info("Player connected", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player)
+	room.broadcastPresence()
+	room.emitEvent(Event{Type: JoinEvent, Player: player, Addr: ws.RemoteAddr().String()})
+
+	// Keep the connection alive and notice when it silently dies. A
+	// connection promoted from the queue already is, and is read through
+	// the reader it had there.
+	read := func() ([]byte, error) {
+		_, data, err := ws.ReadMessage()
+		return data, err
 	}
+	if queued != nil {
+		read = queued.next
+	} else {
+		startKeepalive(ctx, ws)
+	}
+
+	moves := newMoveLimiter(time.Now())
+	chat := newChatLimiter(time.Now())
+	invalid := 0
 
-	log.Printf("Player %s connected. Assigned to %s paddle.", ws.RemoteAddr(), player)
+	input := room.startInputRecording(ws, player)
+	defer input.close()
 
 	// Listen for messages
 	for {
 		var msg Message
-		err := ws.ReadJSON(&msg)
+		data, err := read()
+		if err == nil {
+			input.record(data)
+			err = json.Unmarshal(data, &msg)
+		}
+		if err == nil || isDecodeError(err) {
+			// Any message proves the connection is alive
+			ws.SetReadDeadline(time.Now().Add(PongWait))
+		}
+		if isDecodeError(err) {
+			// One bad frame on a healthy connection doesn't end it, but it
+			// counts toward the invalid message limit
+			invalid++
+			slog.Warn("Malformed message", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "invalid", invalid, "err", err)
+			if invalid > maxInvalidMessages {
+				out.closeWith(websocket.ClosePolicyViolation, "too many invalid messages")
+				break
+			}
+			out.sendJSON(Message{Type: ErrorMessage, Code: MalformedError, Hint: "Message could not be decoded: " + err.Error()})
+			continue
+		}
 		if err != nil {
-			log.Printf("Read error from %s: %v", ws.RemoteAddr(), err)
+			if isClosedConnError(err) {
+				slog.Info("Connection closed", "room", room.ID, "remote_addr", ws.RemoteAddr().String())
+			} else {
+				slog.Warn("Read error", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+			}
 			break
 		}
 
-		log.Printf("Received message from %s: %+v", ws.RemoteAddr(), msg)
+		// A series swaps paddles between games. A player whose paddle was
+		// taken away, by an admin or for being assigned one already held,
+		// is on its way out and can't move it any more.
+		if side := room.checkedPaddleOf(ws); side != "" {
+			player = side
+		} else if player != SpectatorRole {
+			continue
+		}
+
+		slog.Debug("Received message", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "msg_type", msg.Type, "message", msg)
+
+		if err := validateMessage(msg, room.Config); err != nil {
+			invalid++
+			slog.Warn("Invalid message", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "msg_type", msg.Type, "invalid", invalid, "err", err)
+			if invalid > maxInvalidMessages {
+				out.closeWith(websocket.ClosePolicyViolation, "too many invalid messages")
+				break
+			}
+			var msgErr *MessageError
+			errors.As(err, &msgErr)
+			out.sendJSON(Message{Type: ErrorMessage, Code: msgErr.Code, Hint: msgErr.Reason})
+			continue
+		}
+
+		// Spectators can watch, vote and subscribe but not play
+		if player == SpectatorRole && !spectatorMessages[msg.Type] {
+			continue
+		}
 
-		if msg.Type == MoveMessage && msg.Player != "" && msg.Y != nil {
-			gameState.Lock()
-			if msg.Player == "left" {
+		switch {
+		case msg.Type == MoveMessage && msg.Player != "" && msg.Player != player:
+			// A connection may only move the paddle it was assigned
+			slog.Warn("Dropping move for another paddle", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "claimed", msg.Player)
+		case msg.Type == MoveMessage && !moves.allow(time.Now()):
+			// Flooding moves; log once per burst
+			if moves.dropped == 1 {
+				slog.Warn("Throttling moves", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "limit_per_second", maxMovesPerSecond)
+			}
+		case msg.Type == MoveMessage && msg.Direction != nil:
+			// Velocity input; the game loop moves the paddle
+			room.Lock()
+			room.setDirection(player, *msg.Direction)
+			room.ackMove(player, msg.InputSeq)
+			room.Unlock()
+		case msg.Type == MoveMessage && horizontal(player):
+			if msg.X == nil {
+				break
+			}
+			room.Lock()
+			x := room.paddleX(player)
+			clampedX := room.limitJump(player, *x, room.Config.clampXPosition(*msg.X), time.Now())
+			if clampedX != *x {
+				*x = clampedX
+				room.markActivity()
+			}
+			room.ackMove(player, msg.InputSeq)
+			room.Unlock()
+		case msg.Type == MoveMessage && msg.Y != nil:
+			// The paddle is always the connection's own; msg.Player is not trusted
+			room.Lock()
+			if player == "left" {
 				// Clamp Y position
-				clampedY := clampYPosition(*msg.Y)
-				if clampedY != gameState.PanYLeft {
-					gameState.PanYLeft = clampedY
-					log.Printf("Updated left paddle Y to %d", gameState.PanYLeft)
+				clampedY := room.Config.clampYPosition(*msg.Y)
+				if room.hasEffect(MirrorEffect, "left") {
+					clampedY = room.Config.mirrorYPosition(clampedY)
+				}
+				clampedY = room.clampToReach("left", clampedY)
+				clampedY = room.limitJump("left", room.PanYLeft, clampedY, time.Now())
+				if clampedY != room.PanYLeft {
+					room.PanYLeft = clampedY
+					room.markActivity()
+					slog.Debug("Updated paddle", "room", room.ID, "player", "left", "y", room.PanYLeft)
 				}
-			} else if msg.Player == "right" {
+			} else if player == "right" {
 				// Clamp Y position
-				clampedY := clampYPosition(*msg.Y)
-				if clampedY != gameState.PanYRight {
-					gameState.PanYRight = clampedY
-					log.Printf("Updated right paddle Y to %d", gameState.PanYRight)
+				clampedY := room.Config.clampYPosition(*msg.Y)
+				if room.hasEffect(MirrorEffect, "right") {
+					clampedY = room.Config.mirrorYPosition(clampedY)
+				}
+				clampedY = room.clampToReach("right", clampedY)
+				clampedY = room.limitJump("right", room.PanYRight, clampedY, time.Now())
+				if clampedY != room.PanYRight {
+					room.PanYRight = clampedY
+					room.markActivity()
+					slog.Debug("Updated paddle", "room", room.ID, "player", "right", "y", room.PanYRight)
 				}
 			}
-			gameState.Unlock()
+			room.ackMove(player, msg.InputSeq)
+			room.Unlock()
 
 			// No immediate broadcast; game loop handles broadcasting
-		} else {
-			log.Printf("Invalid message from %s: %+v", ws.RemoteAddr(), msg)
+		case msg.Type == AimMessage && msg.Angle != nil:
+			room.Lock()
+			room.aimServe(player, *msg.Angle)
+			room.Unlock()
+		case msg.Type == ServeMessage:
+			room.Lock()
+			room.launchServe(player)
+			room.Unlock()
+		case msg.Type == ColorMessage:
+			color, _ := validateColor(msg.Color)
+			room.Lock()
+			room.setColor(player, color)
+			room.Unlock()
+			slog.Info("Player picked a paddle color", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "color", color)
+			room.broadcastPresence()
+		case msg.Type == VisibilityMsg && (msg.Visibility == Foreground || msg.Visibility == Background):
+			room.Lock()
+			room.setVisibility(player, msg.Visibility)
+			room.Unlock()
+		case msg.Type == SettingsMessage && msg.Physics != "":
+			room.Lock()
+			err := room.requestPhysics(player, msg.Physics)
+			room.Unlock()
+			if err != nil {
+				slog.Warn("Rejected settings", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "err", err)
+			}
+		case msg.Type == VoteMessage:
+			tally, err := room.castVote(ws, msg.Option)
+			if err != nil {
+				slog.Warn("Invalid vote", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+				break
+			}
+			room.broadcastVotes(tally)
+		case (msg.Type == ChatMessage || msg.Type == EmoteMessage) && !chat.allow(time.Now()):
+			if chat.dropped == 1 {
+				slog.Warn("Throttling chat", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player)
+			}
+		case msg.Type == ChatMessage:
+			// Sender comes from the connection, never the message
+			text, _ := cleanChatText(msg.Text)
+			room.relayChat(ChatMessage, player, name, text, "")
+		case msg.Type == EmoteMessage:
+			room.relayChat(EmoteMessage, player, name, "", msg.Emote)
+		case msg.Type == SubscribeMsg && (msg.Subscription == FullSubscription || msg.Subscription == ScoreSubscription):
+			room.clientsMutex.Lock()
+			if msg.Subscription == FullSubscription {
+				delete(room.subscriptions, ws)
+			} else {
+				room.subscriptions[ws] = msg.Subscription
+			}
+			room.clientsMutex.Unlock()
+			slog.Info("Client subscribed", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "subscription", msg.Subscription)
+		default:
+			slog.Warn("Unhandled message", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player, "msg_type", msg.Type)
 		}
 	}
 
 	// Remove client on disconnect
-	clientsMutex.Lock()
-	delete(clients, ws)
-	clientsMutex.Unlock()
+	room.clientsMutex.Lock()
+	delete(room.clients, ws)
+	delete(room.spectators, ws)
+	delete(room.subscriptions, ws)
+	delete(room.binaryClients, ws)
+	room.clientsMutex.Unlock()
+
+	// A connection replaced by a reconnect has already lost its paddle
+	side := room.paddleOf(ws)
+	owned := side != ""
+	if owned {
+		player = side
+	}
+
+	// Dropping out of a match in progress holds the paddle for a while, except
+	// in quad mode where leaving is being eliminated
+	room.Lock()
+	var eliminated *MatchResult
+	paused := false
+	if owned && room.Mode == QuadMode {
+		eliminated = room.eliminate(player)
+	} else {
+		paused = owned && room.pauseForReconnect(player, room.now())
+	}
+	room.Unlock()
+	if paused {
+		room.broadcastPaused(player)
+	}
+	if eliminated != nil {
+		room.broadcastGameOver(*eliminated)
+	}
+
+	room.releasePlayer(ws)
+	room.promoteFromQueue()
+
+	room.dropVote(ws)
+
+	// Hand hosting to whoever is still connected
+	room.clientsMutex.Lock()
+	nextHost := ""
+	for _, role := range room.clients {
+		nextHost = role
+	}
+	room.clientsMutex.Unlock()
+
+	room.Lock()
+	if owned {
+		room.forgetPlayer(player)
+	}
+	if room.Host == player {
+		room.Host = nextHost
+	}
+	if nextHost == "" {
+		// Nobody left to play against the AI
+		room.soloAI = ""
+	}
+	room.Unlock()
+
+	slog.Info("Player disconnected", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", player)
+	room.broadcastPresence()
+	room.emitEvent(Event{Type: LeaveEvent, Player: player, Addr: ws.RemoteAddr().String()})
+}
+
+// abandonJoin undoes the join of a connection that failed before it was told
+// its role. It never played, so unlike a disconnect its paddle isn't held for
+// a reconnect but freed for the next in the queue right away.
+func (room *Room) abandonJoin(ws *websocket.Conn) {
+	room.clientsMutex.Lock()
+	delete(room.clients, ws)
+	delete(room.spectators, ws)
+	delete(room.subscriptions, ws)
+	delete(room.binaryClients, ws)
+	nextHost := ""
+	for _, role := range room.clients {
+		nextHost = role
+	}
+	room.clientsMutex.Unlock()
+
+	side := room.paddleOf(ws)
+	room.releasePlayer(ws)
+	if side == "" {
+		return
+	}
+	slog.Info("Freed paddle of abandoned join", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "player", side)
+
+	room.Lock()
+	room.forgetPlayer(side)
+	if room.Host == side {
+		room.Host = nextHost
+	}
+	if nextHost == "" {
+		room.soloAI = ""
+	}
+	room.Unlock()
+	room.promoteFromQueue()
+}
+
+// forgetPlayer drops what the room keeps about the player on a paddle that
+// was just freed. Caller must hold room lock.
+func (room *Room) forgetPlayer(player string) {
+	room.setVisibility(player, Foreground)
+	room.setReach(player, nil)
+	room.setDirection(player, 0)
+	room.clearAck(player)
+	room.setPlayerName(player, "")
+	room.setColor(player, "")
+	delete(room.lastMoveAt, player)
+	// A new survival player starts their own record
+	room.Rally = 0
+	room.BestRally = 0
+}
+
+// startKeepalive sets the read deadline, extends it on every pong and pings
+// the peer every PingInterval until the returned channel is closed. A missed
+// pong makes the next read fail, which runs the normal disconnect cleanup.
+func startKeepalive(ctx context.Context, ws *websocket.Conn) {
+	ws.SetReadDeadline(time.Now().Add(PongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(PongWait))
+	})
+
+	go func() {
+		ticker := time.NewTicker(PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(PingWait)); err != nil {
+					slog.Info("Ping failed", "remote_addr", ws.RemoteAddr().String(), "err", err)
+					ws.Close()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// gameLoop advances the physics at a fixed step until the room stops. The
+// state goes out separately, from broadcastLoop, at its own rate.
+func (room *Room) gameLoop() {
+	for {
+		select {
+		case <-room.ticker.C:
+			room.runTick()
+		case <-room.done:
+			room.finishRecording()
+			return
+		}
+	}
+}
 
-	assignMutex.Lock()
-	delete(assignedPlayers, ws)
-	assignMutex.Unlock()
+// runTick advances the game by one tick, recovering from any panic so the loop keeps running
+func (room *Room) runTick() {
+	start := time.Now()
+	defer func() { tickDuration.Observe(time.Since(start).Seconds()) }()
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Recovered from panic in game loop", "room", room.ID, "panic", r, "stack", string(debug.Stack()))
+			room.recoverGameState()
+		}
+	}()
 
-	log.Printf("Player %s disconnected.", ws.RemoteAddr())
+	if room.currentPhase() == PhaseIdle {
+		return
+	}
+	// A frozen room only moves when an administrator steps it
+	room.Lock()
+	frozen, now := room.frozen, room.now()
+	room.Unlock()
+	if frozen {
+		return
+	}
+	room.tick(now)
 }
 
-// gameLoop updates the ball's position and broadcasts the game state
-func gameLoop() {
+// tick advances the game to now, announcing any points scored and the result
+// if the match ended, and returns it
+func (room *Room) tick(now time.Time) *MatchResult {
+	room.checkIdle(now)
+	room.advanceState(now)
+	result := room.updateBallPosition(now)
+	room.announcePoints()
+	if result != nil {
+		if result.Intermission {
+			room.broadcastIntermission(*result)
+		} else {
+			room.broadcastGameOver(*result)
+		}
+	}
+	return result
+}
+
+// runBroadcast sends the game state, recovering from any panic so the loop keeps running
+func (room *Room) runBroadcast() {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Recovered from panic in broadcast loop", "room", room.ID, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	// Nothing moves without players, so there's nothing new to send
+	if room.currentPhase() == PhaseIdle {
+		return
+	}
+	room.broadcastGameState()
+}
+
+// broadcastLoop sends the latest game state until the room stops. The room
+// lock keeps it from seeing a half-finished physics tick.
+func (room *Room) broadcastLoop() {
 	for {
-		<-ticker.C
-		updateBallPosition()
-		broadcastGameState()
+		select {
+		case <-room.broadcastTicker.C:
+			room.runBroadcast()
+		case <-room.done:
+			return
+		}
 	}
 }
 
-// updateBallPosition updates the ball's position and handles collisions
-func updateBallPosition() {
-	gameState.Lock()
-	defer gameState.Unlock()
+// Game loop phases
+const (
+	PhaseIdle       = "idle"       // Nobody connected, nothing to simulate or send
+	PhaseHolding    = "holding"    // Clients connected but the ball isn't moving
+	PhaseSimulating = "simulating" // Ball in play
+)
 
-	// Update ball position
-	gameState.Ball.X += gameState.Ball.Vx
-	gameState.Ball.Y += gameState.Ball.Vy
+// currentPhase reports what the game loop should do this tick
+func (room *Room) currentPhase() string {
+	room.Lock()
+	defer room.Unlock()
+
+	room.clientsMutex.Lock()
+	connected := len(room.clients)
+	room.clientsMutex.Unlock()
+
+	phase := PhaseSimulating
+	switch {
+	case connected == 0:
+		phase = PhaseIdle
+	case room.State != StatePlaying || room.pausedForBackground() || room.Serving != "":
+		phase = PhaseHolding
+	}
+	if phase != room.lastPhase {
+		slog.Debug("Game loop phase changed", "room", room.ID, "from", room.lastPhase, "to", phase)
+		room.lastPhase = phase
+	}
+	return phase
+}
+
+// recoverGameState logs the state left behind by a panic and resets the ball
+func (room *Room) recoverGameState() {
+	room.Lock()
+	defer room.Unlock()
+
+	slog.Error("Game state at panic", "room", room.ID, "left_y", room.PanYLeft, "right_y", room.PanYRight,
+		"ball", room.Ball, "effects", len(room.Effects), "rally_hits", room.RallyHits)
+	if snap, err := json.Marshal(room.takeSnapshot()); err == nil {
+		slog.Error("Snapshot for -replay-snapshot", "room", room.ID, "snapshot", string(snap))
+	}
+	room.restartRally()
+}
 
-	// Collision with top wall
-	if gameState.Ball.Y <= 0 {
-		gameState.Ball.Y = 0
-		gameState.Ball.Vy = -gameState.Ball.Vy
+// restartRally puts the ball back in the middle and serves it to a random
+// side, keeping the score. Caller must hold room lock.
+func (room *Room) restartRally() {
+	if room.Mode == QuadMode {
+		room.serveQuad()
+		return
 	}
+	room.resetGame([]string{"left", "right"}[room.rand.Intn(2)])
+}
 
-	// Collision with bottom wall
-	if gameState.Ball.Y >= float64(CanvasHeight) {
-		gameState.Ball.Y = float64(CanvasHeight)
-		gameState.Ball.Vy = -gameState.Ball.Vy
+// updateBallPosition updates the ball's position and handles collisions. It
+// returns the result if the match ended this tick so the caller can announce
+// it once the lock is released; the reset for the next serve happens here,
+// inside the same critical section, so the next broadcast is consistent.
+func (room *Room) updateBallPosition(now time.Time) *MatchResult {
+	room.Lock()
+	defer room.Unlock()
+
+	room.expireEffects(now)
+
+	if room.pausedForBackground() {
+		return nil
 	}
+	room.driveBackgroundPaddles()
+	room.driveSoloOpponent()
+	room.movePaddles()
+	room.trackPaddleVelocity()
 
-	// Collision with left paddle
-	if gameState.Ball.X <= float64(PaddleWidth) {
-		if int(gameState.Ball.Y) >= gameState.PanYLeft && int(gameState.Ball.Y) <= (gameState.PanYLeft+PaddleHeight) {
-			gameState.Ball.X = float64(PaddleWidth)
-			gameState.Ball.Vx = -gameState.Ball.Vx
+	// Ball is held until the match starts and the serving player launches it
+	if room.State != StatePlaying || room.Serving != "" {
+		return nil
+	}
+	if room.servePending(now) {
+		return room.runClock()
+	}
+
+	if room.Mode == QuadMode {
+		out := room.stepQuadBall()
+		if out == "" {
+			return nil
 		}
+		room.markActivity()
+		return room.eliminate(out)
 	}
 
-	// Collision with right paddle
-	if gameState.Ball.X >= float64(CanvasWidth-PaddleWidth) {
-		if int(gameState.Ball.Y) >= gameState.PanYRight && int(gameState.Ball.Y) <= (gameState.PanYRight+PaddleHeight) {
-			gameState.Ball.X = float64(CanvasWidth - PaddleWidth)
-			gameState.Ball.Vx = -gameState.Ball.Vx
+	if result := room.runClock(); result != nil {
+		return result
+	}
+	room.rampSurvival(now)
+	// Every ball moves before any point is scored, so the tick goes into the
+	// rally's replay once, with the ball that ended it where it went out
+	balls := room.balls()
+	conceded := make([]string, len(balls))
+	for i, ball := range balls {
+		conceded[i] = room.stepBall(ball)
+	}
+	room.recordRallyFrame()
+	for i, ball := range balls {
+		conceded := conceded[i]
+		if conceded == "" {
+			continue
+		}
+		room.markActivity()
+		if room.Mode == SurvivalMode {
+			return room.survivalMiss(conceded)
 		}
+		if room.Mode == PracticeMode {
+			room.endStreak()
+		}
+		if len(room.ExtraBalls) == 0 {
+			return room.scorePoint(conceded)
+		}
+		if result := room.scoreBall(ball, conceded); result != nil {
+			return result
+		}
+	}
+	room.updatePowerUps(now)
+	return nil
+}
+
+// MatchResult is the outcome of a finished match
+type MatchResult struct {
+	Winner     string
+	ScoreLeft  int
+	ScoreRight int
+	Duration   time.Duration     // From the first serve
+	Players    map[string]string // Named players by side, as the match started
+	Names      SideNames         // Side names, as the match ended
+	Series     *SeriesScore      // Standing, when playing series
+	// Only a game of a series ended; the players have swapped sides for the next
+	Intermission bool
+	// Survival runs only: the run's length and the player's longest in the room
+	Mode      string
+	Rally     int
+	BestRally int
+}
+
+// survivalRally returns the run's length for broadcasting, or nil for a
+// match of another mode
+func (result MatchResult) survivalRally() *int {
+	if result.Mode != SurvivalMode {
+		return nil
+	}
+	return &result.Rally
+}
+
+// Points needed to win a match
+var winningScore = flag.Int("winning-score", 11, "points needed to win a match")
+
+// scorePoint awards a point against the side that conceded and resets for the
+// next serve. Scores carry over between points and are only zeroed when a side
+// reaches the winning score, in which case the final result is returned.
+// Caller must hold room lock.
+func (room *Room) scorePoint(conceded string) *MatchResult {
+	scorer := room.creditPoint(conceded)
+	room.resetGame(conceded)
+	room.holdServe(conceded)
+	return room.checkMatchOver(scorer)
+}
+
+// scoreBall awards a point for one of several balls leaving the court. Only
+// that ball is sent out again from the serve spot, toward the side that
+// conceded, and the others play on, unless the point won the match. Caller
+// must hold room lock.
+func (room *Room) scoreBall(ball *Ball, conceded string) *MatchResult {
+	scorer := room.creditPoint(conceded)
+	if room.ScoreLeft >= *winningScore || room.ScoreRight >= *winningScore || room.SuddenDeath {
+		room.resetGame(conceded)
+		room.holdServe(conceded)
+	} else {
+		room.spotBall(ball)
+		room.sendBallToward(ball, conceded)
+	}
+	return room.checkMatchOver(scorer)
+}
+
+// creditPoint adds a point for the opponent of the side that conceded and
+// returns who scored. Caller must hold room lock.
+func (room *Room) creditPoint(conceded string) string {
+	scorer := opponent(conceded)
+	if scorer == "left" {
+		room.ScoreLeft++
+	} else {
+		room.ScoreRight++
+	}
+	slog.Info("Point scored", "room", room.ID, "player", scorer, "score_left", room.ScoreLeft, "score_right", room.ScoreRight)
+	room.emitEvent(Event{Type: PointEvent, Player: scorer})
+	pointsCounter.Inc()
+	room.scoredPoints = append(room.scoredPoints, Message{Type: PointMsg, Player: scorer, ScoreLeft: room.ScoreLeft, ScoreRight: room.ScoreRight, Names: room.sideNames()})
+	return scorer
+}
+
+// announcePoints tells everyone about the points scored this tick, ahead of
+// the game over that may follow. Must be called without holding room lock.
+func (room *Room) announcePoints() {
+	room.Lock()
+	points := room.scoredPoints
+	room.scoredPoints = nil
+	room.Unlock()
+
+	for _, msg := range points {
+		room.server.stampEvent(&msg)
+		room.broadcast(msg)
+	}
+}
+
+// checkMatchOver ends the match if the last point reached the winning score
+// or came in sudden death, returning its result. Caller must hold room lock.
+func (room *Room) checkMatchOver(scorer string) *MatchResult {
+	if room.ScoreLeft < *winningScore && room.ScoreRight < *winningScore && !room.SuddenDeath {
+		return nil
 	}
+	return room.endMatch(scorer)
+}
 
-	// Check for game over
-	if gameState.Ball.X < 0 {
-		// Ball touched the left wall, right player wins
-		broadcastGameOver("right")
-		resetGame()
+// endMatch ends the match in progress, or the game of a series, in the
+// winner's favor and returns its result. Caller must hold room lock.
+func (room *Room) endMatch(winner string) *MatchResult {
+	result := &MatchResult{Winner: winner, ScoreLeft: room.ScoreLeft, ScoreRight: room.ScoreRight, Duration: room.now().Sub(room.matchStarted), Players: room.matchPlayers, Names: room.Names}
+	room.ScoreLeft = 0
+	room.ScoreRight = 0
+	room.resetClock()
+	room.State = StateGameOver
+	if room.inSeries() {
+		clinched := room.winSeriesGame(winner)
+		if !clinched {
+			room.swapSides()
+			result.Series = room.seriesScore()
+			result.Intermission = true
+			return result
+		}
+		result.Series = room.seriesScore()
+		room.endSeries()
 	}
-	if gameState.Ball.X > float64(CanvasWidth) {
-		// Ball touched the right wall, left player wins
-		broadcastGameOver("left")
-		resetGame()
+	room.matchStarted = time.Time{}
+	room.matchPlayers = nil
+	return result
+}
+
+// Steepest angle, in degrees from straight across, that a paddle can return the ball at
+var maxBounceAngle = flag.Float64("max-bounce-angle", 60, "steepest return angle in degrees, reached at the ends of the paddle")
+
+// Rally speed-up options. The ball is never allowed to travel more than one
+// paddle width per tick, whatever -max-ball-speed says, so it can't skip past
+// a paddle between ticks.
+var (
+	speedup      = flag.Float64("speedup", 1.05, "factor the ball's speed is multiplied by on paddle hits, every -speedup-every of them (1 disables)")
+	speedupEvery = flag.Int("speedup-every", 1, "paddle hits between speed-ups, for slower-building rallies")
+	maxBallSpeed = flag.Float64("max-ball-speed", 15, "fastest the ball may travel in pixels per tick")
+)
+
+// rampSpeed counts a paddle hit and returns the factor it speeds the ball up
+// by: -speedup on every -speedup-every'th hit of the rally, otherwise 1.
+// Caller must hold room lock.
+func (room *Room) rampSpeed() float64 {
+	room.speedHits++
+	if room.speedHits%*speedupEvery != 0 {
+		return 1
 	}
+	room.SpeedTier++
+	return *speedup
 }
 
-// resetGame resets the ball to the center after a game over
-func resetGame() {
-	gameState.Ball.X = float64(CanvasWidth / 2)
-	gameState.Ball.Y = float64(CanvasHeight / 2)
-	// Reset velocity; you can randomize direction if desired
-	gameState.Ball.Vx = 4.0
-	gameState.Ball.Vy = 4.0
+// speedCap returns the fastest the ball may travel. Caller must hold room lock.
+func (room *Room) speedCap() float64 {
+	return math.Min(*maxBallSpeed, float64(room.Config.PaddleWidth))
+}
+
+// deflect returns a ball off a side's paddle, sending it horizontally in direction (1 right, -1 left). Where it struck sets the
+// angle: the center returns it straight, the top edge up and the bottom edge
+// down at maxBounceAngle. Each return speeds the ball up, to at most
+// speedCap. Caller must hold room lock.
+func (room *Room) deflect(ball *Ball, side string, direction float64) {
+	top, length := room.paddleSpan(side)
+	half := length / 2
+	offset := (ball.Y - (top + half)) / half
+	offset = math.Max(-1, math.Min(1, offset))
+
+	speed := math.Hypot(ball.Vx, ball.Vy) * room.rampSpeed()
+	speed = math.Min(speed, room.speedCap())
+	sin, cos := math.Sincos(offset * *maxBounceAngle * math.Pi / 180)
+	ball.Vx = direction * speed * cos
+	ball.Vy = speed * sin
+}
+
+// contacts records everything the ball touched during one tick
+type contacts struct {
+	top, bottom             bool // Walls
+	leftPaddle, rightPaddle bool
+	leftExit, rightExit     bool // Ball crossed a back wall
+	// Set when the ball's path crossed a paddle's face during the tick, with
+	// the Y it crossed at
+	swept  bool
+	sweptY float64
+}
+
+// sweptPaddleHit reports whether a ball, moving from (fromX, fromY) to its
+// current position, crossed the face of a side's paddle. faceX is where the ball's center is when its edge touches the
+// face. A fast ball can pass through a paddle between ticks without ever
+// overlapping it, so the path is checked rather than just the end point.
+// Returns the Y the ball crossed at. Caller must hold room lock.
+func (room *Room) sweptPaddleHit(ball *Ball, fromX, fromY, faceX float64, side string) (float64, bool) {
+	if (fromX-faceX)*(ball.X-faceX) > 0 || fromX == ball.X {
+		return 0, false // Didn't cross the face this tick
+	}
+	t := (fromX - faceX) / (fromX - ball.X)
+	y := fromY + t*(ball.Y-fromY)
+	top, length := room.paddleSpan(side)
+	return y, y >= top-BallRadius && y <= top+length+BallRadius
+}
+
+// stepBall advances a ball by one tick and resolves its collisions. It
+// returns the side whose back wall the ball went out through, or "" if the ball
+// is still in play. Caller must hold room lock.
+//
+// Collisions are resolved in two phases so that no wall or paddle is favored
+// by the order it is checked in:
+//
+//  1. Move the ball (wind, velocity, portals) and detect every contact against
+//     that single post-move position, or against where the ball's path
+//     crossed a paddle's face if it did.
+//  2. Apply the contacts. Paddles set X/Vx and aim Vy; the top and bottom
+//     walls are applied after them and only force the sign of Vy, so a ball
+//     hitting a paddle and a wall in the same tick still moves away from
+//     both. A ball crossing a back wall only scores if it did not also touch
+//     that side's paddle, and bounces back instead if it left outside the
+//     scoring band.
+func (room *Room) stepBall(ball *Ball) string {
+	width, height := float64(room.Config.Width), float64(room.Config.Height)
+	paddleWidth := float64(room.Config.PaddleWidth)
+
+	room.behavior()(ball)
+
+	// Wind pushes the ball a little every tick
+	ball.Vx += room.Wind.X
+	ball.Vy += room.Wind.Y
+
+	// Update ball position
+	fromX, fromY := ball.X, ball.Y
+	ball.X += ball.Vx
+	ball.Y += ball.Vy
+	movedX, movedY := ball.X, ball.Y
+
+	room.teleportBall(ball)
+	// A teleported ball didn't travel the path in between
+	teleported := ball.X != movedX || ball.Y != movedY
+
+	// Phase 1: detect
+	c := contacts{}
+	if !teleported {
+		switch {
+		case ball.Vx < 0 && !room.walled("left"):
+			c.sweptY, c.swept = room.sweptPaddleHit(ball, fromX, fromY, paddleWidth+BallRadius, "left")
+			c.leftPaddle = c.swept
+		case ball.Vx > 0 && !room.walled("right"):
+			c.sweptY, c.swept = room.sweptPaddleHit(ball, fromX, fromY, width-paddleWidth-BallRadius, "right")
+			c.rightPaddle = c.swept
+		}
+	}
+	// A practice wall has no paddle in front of it
+	c.leftPaddle = c.leftPaddle || ball.Vx < 0 && !room.walled("left") && room.ballHitsPaddle(ball, "left", 0)
+	c.rightPaddle = c.rightPaddle || ball.Vx > 0 && !room.walled("right") && room.ballHitsPaddle(ball, "right", width-paddleWidth)
+	c.leftExit = ball.X < 0 && !c.leftPaddle
+	c.rightExit = ball.X > width && !c.rightPaddle
+
+	// A swept hit is resolved where the ball met the paddle
+	wallY := ball.Y
+	if c.swept {
+		wallY = c.sweptY
+	}
+	c.top = wallY <= BallRadius
+	c.bottom = wallY >= height-BallRadius
+
+	// Phase 2: apply
+	bounced := false
+	conceded := ""
+	switch {
+	case c.leftPaddle:
+		ball.X = paddleWidth + BallRadius
+		if c.swept {
+			ball.Y = c.sweptY
+		}
+		room.deflect(ball, "left", 1)
+		room.applySpin(ball, "left")
+		room.lastHitter = "left"
+		bounced = true
+		room.RallyHits++
+		room.countReturn("left")
+		room.maybeMirrorOpponent("left")
+		room.emitEvent(Event{Type: HitEvent, Player: "left"})
+	case c.rightPaddle:
+		ball.X = width - paddleWidth - BallRadius
+		if c.swept {
+			ball.Y = c.sweptY
+		}
+		room.deflect(ball, "right", -1)
+		room.applySpin(ball, "right")
+		room.lastHitter = "right"
+		bounced = true
+		room.RallyHits++
+		room.countReturn("right")
+		room.maybeMirrorOpponent("right")
+		room.emitEvent(Event{Type: HitEvent, Player: "right"})
+	case c.leftExit && (!inScoringBand(room.Config, ball.Y) || room.walled("left")):
+		// Outside the scoring band, or off a practice wall, bounce back into play
+		ball.X = 0
+		ball.Vx = math.Abs(ball.Vx)
+		bounced = true
+	case c.rightExit && (!inScoringBand(room.Config, ball.Y) || room.walled("right")):
+		ball.X = width
+		ball.Vx = -math.Abs(ball.Vx)
+		bounced = true
+	case c.leftExit:
+		conceded = "left"
+	case c.rightExit:
+		conceded = "right"
+	}
+
+	// Walls go last so a paddle deflection can't send the ball into one
+	switch {
+	case c.top:
+		ball.Y = BallRadius
+		ball.Vy = math.Abs(ball.Vy)
+		bounced = true
+	case c.bottom:
+		ball.Y = height - BallRadius
+		ball.Vy = -math.Abs(ball.Vy)
+		bounced = true
+	}
+
+	// Per-bounce effects run once per tick however many surfaces were hit
+	if bounced {
+		room.onBounce(ball)
+	}
+
+	return conceded
+}
+
+// resetGame resets the ball to the center and serves it toward the receiver,
+// normally the player who just lost the point, once the serve delay is up
+func (room *Room) resetGame(receiver string) {
+	room.endRally()
+	room.lastHitter = ""
+	room.speedHits = 0
+	room.SpeedTier = 0
+
+	room.spotBall(&room.Ball)
+	for i := range room.ExtraBalls {
+		room.spotBall(&room.ExtraBalls[i])
+	}
+	room.applyPendingPhysics()
+	if *alternateServe {
+		receiver = opponent(room.receiver)
+	}
+	room.receiver = receiver
+	if *serveDelay <= 0 {
+		room.serveBalls()
+		return
+	}
+	room.stopBalls()
+	room.serveAt = room.now().Add(*serveDelay)
+}
+
+// spotBall puts a ball back where serves start, the middle of the court
+// unless configured otherwise. Caller must hold room lock.
+func (room *Room) spotBall(ball *Ball) {
+	ball.X = room.Config.BallStartX
+	ball.Y = room.Config.BallStartY
+	ball.portalExit = nil
 }
 
 func main() {
-	// Set up the WebSocket route
-	http.HandleFunc("/ws", handleConnections)
+	flag.Parse()
 
-	// Serve static files from the "public" directory
-	fs := http.FileServer(http.Dir("./public"))
-	http.Handle("/", fs)
+	if err := setupLogging(); err != nil {
+		log.Fatal(err)
+	}
 
-	// Start the game loop
-	go gameLoop()
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal("Invalid board configuration: ", err)
+	}
 
-	// Start the server
-	log.Println("Server started on :8080")
-	err := http.ListenAndServe(":8080", nil)
+	if *minClientVersion != "" {
+		if _, err := parseVersion(*minClientVersion); err != nil {
+			log.Fatal("Invalid -min-client-version:", err)
+		}
+	}
+
+	if _, err := parseSideNames(*leftName, *rightName); err != nil {
+		log.Fatal("Invalid side name: ", err)
+	}
+
+	if *winningScore < 1 {
+		log.Fatal("-winning-score must be at least 1")
+	}
+	if *seriesLength < 1 || *seriesLength%2 == 0 {
+		log.Fatal("-series-length must be a positive odd number")
+	}
+
+	if _, ok := aiLevels[*aiDifficulty]; !ok {
+		log.Fatalf("Invalid -ai-difficulty %q", *aiDifficulty)
+	}
+
+	switch *backgroundMode {
+	case "none", "pause", "ai":
+	default:
+		log.Fatalf("Invalid -background-mode %q", *backgroundMode)
+	}
+
+	if *practiceWall != "left" && *practiceWall != "right" {
+		log.Fatalf("Invalid -practice-wall %q: must be left or right", *practiceWall)
+	}
+
+	if *maxBounceAngle < 0 || *maxBounceAngle >= 90 {
+		log.Fatalf("Invalid -max-bounce-angle %v: must be at least 0 and below 90", *maxBounceAngle)
+	}
+
+	if *resyncFrames < 1 {
+		log.Fatalf("Invalid -resync-frames %d: must be at least 1", *resyncFrames)
+	}
+
+	if *broadcastWorkers < 1 {
+		log.Fatalf("Invalid -broadcast-workers %d: must be at least 1", *broadcastWorkers)
+	}
+
+	if *speedupEvery < 1 {
+		log.Fatalf("Invalid -speedup-every %d: must be at least 1", *speedupEvery)
+	}
+	if *speedup < 1 {
+		log.Fatalf("Invalid -speedup %v: must be at least 1", *speedup)
+	}
+	if *maxBallSpeed <= 0 {
+		log.Fatalf("Invalid -max-ball-speed %v: must be positive", *maxBallSpeed)
+	}
+
+	// Every character of a chat line may be escaped as \uXXXX
+	if *maxChatLength < 1 || *maxChatLength*6 > MaxMessageSize/2 {
+		log.Fatalf("Invalid -max-chat-length %d: must be between 1 and %d", *maxChatLength, MaxMessageSize/12)
+	}
+
+	if err := validateCompressionLevel(*compressionLevel); err != nil {
+		log.Fatal("Invalid -compression-level: ", err)
+	}
+
+	cert, key, err := tlsFiles()
+	if err != nil {
+		log.Fatal("TLS: ", err)
+	}
+
+	if *feedInput != "" {
+		if err := feedRecordedInput(*feedInput, *feedURL); err != nil {
+			log.Fatal("Feeding input: ", err)
+		}
+		return
+	}
+
+	if *replaySnapshot != "" {
+		if err := replayFromSnapshot(cfg, *replaySnapshot, *replayTicks, os.Stdout); err != nil {
+			log.Fatal("Replay: ", err)
+		}
+		return
+	}
+
+	store, err := openJSONStatsStore(*statsFile)
 	if err != nil {
+		log.Fatal("Player statistics: ", err)
+	}
+	srv := NewServer(cfg, store)
+	srv.upgrader.EnableCompression = compressionEnabled()
+
+	if *eventsFile != "" {
+		if err := srv.startEventExport(*eventsFile); err != nil {
+			log.Fatal("Event export: ", err)
+		}
+	}
+
+	if *recordDir != "" {
+		if err := os.MkdirAll(*recordDir, 0o755); err != nil {
+			log.Fatal("Recording: ", err)
+		}
+		slog.Info("Recording matches", "dir", *recordDir)
+	}
+	if *recordInput != "" {
+		if err := os.MkdirAll(*recordInput, 0o755); err != nil {
+			log.Fatal("Recording input: ", err)
+		}
+		slog.Info("Recording input", "dir", *recordInput)
+	}
+
+	// A replay replaces the live games
+	var frames []RecordedFrame
+	if *replayFile != "" {
+		frames, err = loadRecording(*replayFile)
+		if err != nil {
+			log.Fatal("Replay: ", err)
+		}
+		slog.Info("Replaying recorded match", "file", *replayFile, "frames", len(frames))
+	}
+
+	// Start the server
+	server := &http.Server{Addr: ":8080", Handler: srv.Handler(frames)}
+	go srv.shutdownOnSignal(server)
+
+	if cert != "" {
+		slog.Info("Server started", "addr", ":8080", "scheme", "https", "websocket", "wss", "cert", cert)
+		err = server.ListenAndServeTLS(cert, key)
+	} else {
+		slog.Info("Server started", "addr", ":8080", "scheme", "http", "websocket", "ws")
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal("ListenAndServe:", err)
 	}
+	<-shutdownDone
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"math"
+	"time"
+)
+
+// Match states. The ball only moves while playing.
+const (
+	StateWaiting   = "waiting"   // A paddle is still free
+	StateCountdown = "countdown" // Both paddles taken, counting down to the first serve
+	StatePlaying   = "playing"
+	StateGameOver  = "gameover" // A match just ended; the next one counts down when ready
+	StatePaused    = "paused"   // A player dropped mid-match; waiting for them to return
+)
+
+// Seconds counted down before a match starts
+const countdownSeconds = 3
+
+// ready reports whether both paddles have someone (or the solo AI) to play
+// them, or in quad mode whether at least two do. A practice player needs
+// nobody. Caller must hold room lock.
+func (room *Room) ready() bool {
+	// Nobody connects to a stepped room; its paddles are driven directly
+	if room.stepped {
+		return true
+	}
+	room.assignMutex.Lock()
+	players := len(room.assignedPlayers)
+	room.assignMutex.Unlock()
+
+	if room.Mode == QuadMode {
+		return players >= 2
+	}
+	return players == 2 || players == 1 && (room.soloAI != "" || room.Mode == PracticeMode)
+}
+
+// advanceState moves the room through its match states and announces each
+// second of the countdown
+func (room *Room) advanceState(now time.Time) {
+	room.Lock()
+	from := room.State
+	var forfeit *MatchResult
+	if room.State == StatePaused {
+		forfeit = room.checkPause(now)
+	}
+	count := 0
+	switch {
+	case room.State == StatePaused:
+	case !room.ready():
+		if room.State != StateWaiting {
+			// A series doesn't carry over to a new opponent
+			room.endSeries()
+		}
+		room.State = StateWaiting
+	case room.State == StateWaiting || room.State == StateGameOver:
+		room.State = StateCountdown
+		room.countdownEnds = now.Add(countdownSeconds * time.Second)
+		room.countdownShown = 0
+	}
+	if room.State == StateCountdown {
+		remaining := int(math.Ceil(room.countdownEnds.Sub(now).Seconds()))
+		if remaining <= 0 {
+			room.State = StatePlaying
+			// A match resumed after a reconnect keeps its start
+			if room.matchStarted.IsZero() {
+				room.matchStarted = now
+				room.matchPlayers = maps.Clone(room.PlayerNames)
+				room.applyVotes()
+				if room.Mode == QuadMode {
+					room.startQuadMatch()
+				}
+			}
+			room.markActivity()
+		} else if remaining != room.countdownShown {
+			room.countdownShown = remaining
+			count = remaining
+		}
+	}
+	to := room.State
+	room.Unlock()
+
+	if to != from {
+		slog.Info("Match state changed", "room", room.ID, "from", from, "to", to)
+	}
+	if forfeit != nil {
+		room.broadcastGameOver(*forfeit)
+		// The freed paddle can go to whoever is waiting
+		room.promoteFromQueue()
+	}
+	if from == StateCountdown && to == StatePlaying {
+		room.broadcastGameStart()
+	}
+	if count > 0 {
+		msg := Message{Type: CountdownMsg, Count: count}
+		room.server.stampEvent(&msg)
+		room.broadcast(msg)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Operational metrics, served on /metrics
+var (
+	connectionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pong_connections",
+		Help: "Open WebSocket connections.",
+	})
+	roomsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pong_rooms",
+		Help: "Rooms in progress.",
+	})
+	playersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pong_players",
+		Help: "Connections currently assigned a paddle.",
+	})
+	matchesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pong_matches_total",
+		Help: "Matches played to completion, including forfeits.",
+	})
+	pointsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pong_points_total",
+		Help: "Points scored.",
+	})
+	droppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pong_messages_dropped_total",
+		Help: "Messages that failed to write to a client.",
+	})
+	rejectedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pong_connections_rejected_total",
+		Help: "WebSocket upgrades refused because their address had too many connections.",
+	})
+	tickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pong_tick_duration_seconds",
+		Help:    "Time spent on one game loop tick.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 10), // 0.1ms to ~51ms
+	})
+)
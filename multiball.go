@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Most balls a room may play with, chosen by whoever creates it with
+// /ws?balls=<n>. Every ball scores when it leaves the court; only the one
+// that left is sent out again, so play carries on with the others. Quad
+// rooms always play with one.
+const maxBalls = 2
+
+// parseBalls validates the balls query parameter; empty means one
+func parseBalls(value string) (int, error) {
+	if value == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxBalls {
+		return 0, fmt.Errorf("balls must be between 1 and %d", maxBalls)
+	}
+	return n, nil
+}
+
+// balls returns every ball in play, the served one first. Caller must hold
+// room lock.
+func (room *Room) balls() []*Ball {
+	balls := []*Ball{&room.Ball}
+	for i := range room.ExtraBalls {
+		balls = append(balls, &room.ExtraBalls[i])
+	}
+	return balls
+}
+
+// ballPositions returns where every ball is for broadcasting, or nil with a
+// single ball, which ballX and ballY already carry. Caller must hold room lock.
+func (room *Room) ballPositions() []Vector {
+	if len(room.ExtraBalls) == 0 {
+		return nil
+	}
+	positions := make([]Vector, 0, 1+len(room.ExtraBalls))
+	for _, ball := range room.balls() {
+		positions = append(positions, Vector{X: ball.X, Y: ball.Y})
+	}
+	return positions
+}
+
+// incomingBall returns the ball a side's paddle should play: the nearest one
+// heading its way, or the served ball if none is. Caller must hold room lock.
+func (room *Room) incomingBall(side string) *Ball {
+	wallX, heading := 0.0, -1.0
+	if side == "right" {
+		wallX, heading = float64(room.Config.Width), 1
+	}
+	nearest := &room.Ball
+	best := math.Inf(1)
+	for _, ball := range room.balls() {
+		if ball.Vx*heading <= 0 {
+			continue
+		}
+		if dist := math.Abs(wallX - ball.X); dist < best {
+			nearest, best = ball, dist
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest side name accepted, in characters
+const maxSideNameLength = 24
+
+// Default display names for the two sides, e.g. team names. A room takes
+// its own from the left-name and right-name parameters of the connection
+// that creates it.
+var (
+	leftName  = flag.String("left-name", "Left", "default display name for the left side")
+	rightName = flag.String("right-name", "Right", "default display name for the right side")
+)
+
+// SideNames carries the display names for both sides
+type SideNames struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// validateSideName trims a name and checks its length and characters
+func validateSideName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is empty")
+	}
+	if utf8.RuneCountInString(name) > maxSideNameLength {
+		return "", fmt.Errorf("name %q is longer than %d characters", name, maxSideNameLength)
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("name %q contains unprintable characters", name)
+		}
+	}
+	return name, nil
+}
+
+// parseSideNames validates a room's side names
+func parseSideNames(left, right string) (SideNames, error) {
+	l, err := validateSideName(left)
+	if err != nil {
+		return SideNames{}, fmt.Errorf("left: %w", err)
+	}
+	r, err := validateSideName(right)
+	if err != nil {
+		return SideNames{}, fmt.Errorf("right: %w", err)
+	}
+	return SideNames{Left: l, Right: r}, nil
+}
+
+// sideNames returns the room's side names for a message. Caller must hold
+// room lock.
+func (room *Room) sideNames() *SideNames {
+	names := room.Names
+	return &names
+}
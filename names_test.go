@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseSideNames(t *testing.T) {
+	tests := []struct {
+		left, right string
+		want        SideNames
+		wantErr     bool
+	}{
+		{left: "Red", right: "Blue", want: SideNames{Left: "Red", Right: "Blue"}},
+		{left: "  Red ", right: "Blue\t", want: SideNames{Left: "Red", Right: "Blue"}},
+		{left: "", right: "Blue", wantErr: true},
+		{left: "Red", right: "   ", wantErr: true},
+		{left: strings.Repeat("x", maxSideNameLength+1), right: "Blue", wantErr: true},
+		{left: "Red", right: "Bl\x00ue", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSideNames(tt.left, tt.right)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSideNames(%q, %q) error = %v, want error %v", tt.left, tt.right, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSideNames(%q, %q) = %+v, want %+v", tt.left, tt.right, got, tt.want)
+		}
+	}
+}
+
+func TestSideNamesInGameOver(t *testing.T) {
+	setFlag(t, winningScore, 1)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+
+	left := dialTest(t, ts, "?left-name=Red&right-name=Blue")
+	readUntil(t, left, AssignMessage)
+	// Names given by a later connection don't rename the room
+	right := dialTest(t, ts, "?left-name=Green&right-name=Gold")
+	readUntil(t, right, AssignMessage)
+
+	want := SideNames{Left: "Red", Right: "Blue"}
+	if msg := readUntil(t, right, UpdateMessage); msg.Names == nil || *msg.Names != want {
+		t.Fatalf("update names = %+v, want %+v", msg.Names, want)
+	}
+	for _, over := range playOut(t, cfg, GameOverMsg, left, right) {
+		if over.Names == nil || *over.Names != want {
+			t.Fatalf("game over names = %+v, want %+v", over.Names, want)
+		}
+	}
+}
+
+func TestInvalidSideNameRejected(t *testing.T) {
+	_, ts := newTestServer(t, testConfig(t))
+	resp, err := http.Get(ts.URL + "/ws?left-name=" + strings.Repeat("x", maxSideNameLength+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Origin options. With no allowlist only pages served by this server may connect.
+var (
+	allowedOrigins = flag.String("allowed-origins", "", "comma-separated origins allowed to connect, e.g. https://pong.example.com (empty allows same-origin only)")
+	allowNoOrigin  = flag.Bool("allow-no-origin", true, "allow connections without an Origin header, such as non-browser clients")
+)
+
+// checkOrigin accepts same-origin requests, allowlisted origins and, if
+// enabled, requests without an Origin header. Rejected upgrades get a 403.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return *allowNoOrigin
+	}
+	for _, allowed := range strings.Split(*allowedOrigins, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	slog.Warn("Rejecting connection from disallowed origin", "remote_addr", r.RemoteAddr, "origin", origin)
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	setFlag(t, allowedOrigins, "https://pong.example.com, https://other.example.com")
+	tests := []struct {
+		name     string
+		origin   string
+		noOrigin bool
+		want     bool
+	}{
+		{"allowlisted", "https://pong.example.com", true, true},
+		{"allowlisted in any case", "HTTPS://Other.Example.com", true, true},
+		{"same origin", "http://game.local:8080", true, true},
+		{"disallowed", "https://evil.example.com", true, false},
+		{"allowlisted host on another scheme", "http://pong.example.com", true, false},
+		{"missing, allowed", "", true, true},
+		{"missing, denied", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, allowNoOrigin, tt.noOrigin)
+			r, err := http.NewRequest(http.MethodGet, "http://game.local:8080/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin with Origin %q = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisallowedOriginGets403(t *testing.T) {
+	setFlag(t, allowedOrigins, "https://pong.example.com")
+	setFlag(t, allowNoOrigin, false)
+	_, ts := newTestServer(t, testConfig(t))
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"https://pong.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL(ts, ""), header)
+		if conn != nil {
+			conn.Close()
+		}
+		if resp == nil {
+			t.Fatalf("origin %q: %v", tt.origin, err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("origin %q got status %d, want %d", tt.origin, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// How long a player who drops mid-match has to reconnect before forfeiting
+var reconnectGrace = flag.Duration("reconnect-grace", 30*time.Second, "how long to hold a dropped player's paddle before they forfeit (0 frees it at once)")
+
+// pauseForReconnect pauses a match in progress when a player drops, holding
+// their paddle for the grace period. It reports whether the match was
+// paused. Caller must hold room lock.
+func (room *Room) pauseForReconnect(player string, now time.Time) bool {
+	if *reconnectGrace <= 0 || room.State != StatePlaying || (player != "left" && player != "right") {
+		return false
+	}
+	room.State = StatePaused
+	room.Missing = player
+	room.graceEnds = now.Add(*reconnectGrace)
+
+	room.assignMutex.Lock()
+	room.heldSide = player
+	room.assignMutex.Unlock()
+
+	slog.Info("Match paused, holding paddle", "room", room.ID, "player", player, "grace", *reconnectGrace)
+	return true
+}
+
+// broadcastPaused tells everyone which side the match is waiting for
+func (room *Room) broadcastPaused(player string) {
+	msg := Message{
+		Type:   PausedMsg,
+		Player: player,
+		Hint:   fmt.Sprintf("The %s player disconnected. Waiting %d seconds for them to return.", player, int(reconnectGrace.Seconds())),
+	}
+	room.server.stampEvent(&msg)
+	room.broadcast(msg)
+}
+
+// slotHeld reports whether a paddle is being held for a dropped player
+func (room *Room) slotHeld() bool {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+	return room.heldSide != ""
+}
+
+// checkPause resumes a paused match once the missing player is back, or
+// forfeits it to the other player when the grace period runs out. It returns
+// the forfeit result, if any. Caller must hold room lock.
+func (room *Room) checkPause(now time.Time) *MatchResult {
+	room.assignMutex.Lock()
+	reclaimed := room.heldSide == ""
+	expired := !reclaimed && !now.Before(room.graceEnds)
+	if expired {
+		room.heldSide = ""
+		delete(room.tokens, room.Missing)
+	}
+	room.assignMutex.Unlock()
+
+	missing := room.Missing
+	switch {
+	case reclaimed:
+		slog.Info("Player is back", "room", room.ID, "player", missing)
+		room.Missing = ""
+		room.State = StateWaiting // Counts down again once both are ready
+		return nil
+	case !expired:
+		return nil
+	}
+
+	room.Missing = ""
+	room.State = StateWaiting
+	winner := opponent(missing)
+	if !room.sideAssigned(winner) {
+		slog.Info("Grace period over with nobody left to win", "room", room.ID)
+		return nil
+	}
+	slog.Info("Player didn't return, match forfeited", "room", room.ID, "player", missing, "winner", winner)
+	result := &MatchResult{Winner: winner, ScoreLeft: room.ScoreLeft, ScoreRight: room.ScoreRight, Duration: now.Sub(room.matchStarted), Players: room.matchPlayers, Names: room.Names}
+	room.matchStarted = time.Time{}
+	room.matchPlayers = nil
+	room.endSeries()
+	room.ScoreLeft = 0
+	room.ScoreRight = 0
+	room.resetClock()
+	room.resetGame(missing)
+	room.State = StateGameOver
+	return result
+}
+
+// sideAssigned reports whether someone holds the given paddle
+func (room *Room) sideAssigned(side string) bool {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+
+	for _, role := range room.assignedPlayers {
+		if role == side {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+// scene is a ball and the paddles around it for one physics tick
+type scene struct {
+	ball          Ball
+	leftY, rightY int
+}
+
+// play steps a scene's ball once and returns where it ended up and who conceded
+func play(t *testing.T, s scene) (Ball, string) {
+	t.Helper()
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	room.PanYLeft, room.PanYRight = s.leftY, s.rightY
+	room.Ball = s.ball
+	conceded := room.stepBall(&room.Ball)
+	return room.Ball, conceded
+}
+
+// mirrorSides reflects a scene left to right
+func mirrorSides(cfg Config, s scene) scene {
+	b := s.ball
+	return scene{
+		ball:   Ball{X: float64(cfg.Width) - b.X, Y: b.Y, Vx: -b.Vx, Vy: b.Vy},
+		leftY:  s.rightY,
+		rightY: s.leftY,
+	}
+}
+
+// mirrorEnds reflects a scene top to bottom
+func mirrorEnds(cfg Config, s scene) scene {
+	b := s.ball
+	return scene{
+		ball:   Ball{X: b.X, Y: float64(cfg.Height) - b.Y, Vx: b.Vx, Vy: -b.Vy},
+		leftY:  cfg.MaxPaddleY() - s.leftY,
+		rightY: cfg.MaxPaddleY() - s.rightY,
+	}
+}
+
+func TestPhysicsMirrorSymmetric(t *testing.T) {
+	cfg := testConfig(t)
+	pw, ph := float64(cfg.PaddleWidth), float64(cfg.PaddleHeight)
+	mid := cfg.MaxPaddleY() / 2
+	scenes := map[string]scene{
+		"paddle face":              {ball: Ball{X: pw + BallRadius + 3, Y: float64(mid) + ph/3, Vx: -5, Vy: 2}, leftY: mid, rightY: mid},
+		"paddle and wall at once":  {ball: Ball{X: pw + BallRadius + 3, Y: BallRadius + 2, Vx: -5, Vy: -4}, leftY: 0, rightY: mid},
+		"paddle corner":            {ball: Ball{X: pw + 6 + 3, Y: float64(mid) - 8 + 4, Vx: -3, Vy: 4}, leftY: mid, rightY: mid},
+		"fast ball through paddle": {ball: Ball{X: pw + BallRadius + 20, Y: float64(mid) + ph/2, Vx: -40, Vy: 0}, leftY: mid, rightY: mid},
+		"missed":                   {ball: Ball{X: 2, Y: float64(mid) + ph/2, Vx: -5, Vy: 1}, leftY: 0, rightY: 0},
+		"corner exit":              {ball: Ball{X: 2, Y: BallRadius + 1, Vx: -5, Vy: -5}, leftY: cfg.MaxPaddleY(), rightY: mid},
+	}
+	for name, s := range scenes {
+		t.Run(name, func(t *testing.T) {
+			base, conceded := play(t, s)
+			mirrors := []struct {
+				name  string
+				scene scene
+				flipX bool
+				flipY bool
+			}{
+				{"left-right", mirrorSides(cfg, s), true, false},
+				{"top-bottom", mirrorEnds(cfg, s), false, true},
+				{"both", mirrorEnds(cfg, mirrorSides(cfg, s)), true, true},
+			}
+			for _, m := range mirrors {
+				got, gotConceded := play(t, m.scene)
+				want, wantConceded := base, conceded
+				if m.flipX {
+					want.X, want.Vx = float64(cfg.Width)-want.X, -want.Vx
+					if wantConceded != "" {
+						wantConceded = opponent(wantConceded)
+					}
+				}
+				if m.flipY {
+					want.Y, want.Vy = float64(cfg.Height)-want.Y, -want.Vy
+				}
+				if gotConceded != wantConceded {
+					t.Errorf("%s mirror conceded %q, want %q", m.name, gotConceded, wantConceded)
+				}
+				if !near(got.X, want.X) || !near(got.Y, want.Y) || !near(got.Vx, want.Vx) || !near(got.Vy, want.Vy) {
+					t.Errorf("%s mirror left the ball at (%v, %v) moving (%v, %v), want (%v, %v) moving (%v, %v)",
+						m.name, got.X, got.Y, got.Vx, got.Vy, want.X, want.Y, want.Vx, want.Vy)
+				}
+			}
+		})
+	}
+}
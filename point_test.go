@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestPointAnnouncedBeforeGameOver(t *testing.T) {
+	setFlag(t, winningScore, 1)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+
+	points := playOut(t, cfg, PointMsg, left, right)
+	scorer := points[0].Player
+	if scorer != "left" && scorer != "right" {
+		t.Fatalf("point scored by %q", scorer)
+	}
+	wantLeft, wantRight := 1, 0
+	if scorer == "right" {
+		wantLeft, wantRight = 0, 1
+	}
+	for i, point := range points {
+		if point.Player != scorer || point.ScoreLeft != wantLeft || point.ScoreRight != wantRight {
+			t.Fatalf("player %d got point %+v, want %s scoring to %d-%d", i, point, scorer, wantLeft, wantRight)
+		}
+	}
+
+	// The match point is followed by the game over and no other point
+	for _, conn := range []*websocket.Conn{left, right} {
+		for {
+			msg, err := readMessage(conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type == PointMsg {
+				t.Fatalf("second point %+v before the game over", msg)
+			}
+			if msg.Type == GameOverMsg {
+				if msg.Winner != scorer || msg.ScoreLeft != wantLeft || msg.ScoreRight != wantRight {
+					t.Fatalf("game over %+v, want %s winning %d-%d", msg, scorer, wantLeft, wantRight)
+				}
+				break
+			}
+		}
+	}
+}
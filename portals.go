@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"math"
+)
+
+// Portal is a circular region of the court
+type Portal struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+// PortalPair links two portals; a ball entering either one leaves through the other
+type PortalPair struct {
+	A        Portal  `json:"a"`
+	B        Portal  `json:"b"`
+	Rotation float64 `json:"rotation"` // Radians applied to the ball's velocity on teleport
+}
+
+// contains reports whether a point lies inside the portal
+func (p Portal) contains(x, y float64) bool {
+	return math.Hypot(x-p.X, y-p.Y) <= p.Radius
+}
+
+// Portal options
+var (
+	portalsEnabled = flag.Bool("portals", false, "place a pair of teleporting portals on the court")
+	portalRotation = flag.Float64("portal-rotation", 0, "degrees to rotate the ball's velocity when it passes through a portal")
+)
+
+// defaultPortals places one portal in the upper left and one in the lower right of the center
+func defaultPortals(cfg Config) []PortalPair {
+	return []PortalPair{{
+		A:        Portal{X: float64(cfg.Width)/2 - 150, Y: float64(cfg.Height) / 4, Radius: 30},
+		B:        Portal{X: float64(cfg.Width)/2 + 150, Y: float64(cfg.Height) * 3 / 4, Radius: 30},
+		Rotation: *portalRotation * math.Pi / 180,
+	}}
+}
+
+// teleportBall moves a ball through any portal it has entered. Caller must hold room lock.
+func (room *Room) teleportBall(ball *Ball) {
+	if exit := ball.portalExit; exit != nil {
+		if exit.contains(ball.X, ball.Y) {
+			return
+		}
+		ball.portalExit = nil
+	}
+
+	for i := range room.Portals {
+		pair := &room.Portals[i]
+		var from, to *Portal
+		switch {
+		case pair.A.contains(ball.X, ball.Y):
+			from, to = &pair.A, &pair.B
+		case pair.B.contains(ball.X, ball.Y):
+			from, to = &pair.B, &pair.A
+		default:
+			continue
+		}
+
+		// Keep the ball's offset from the portal center so it emerges on the same line
+		ball.X = to.X + (ball.X - from.X)
+		ball.Y = to.Y + (ball.Y - from.Y)
+		if pair.Rotation != 0 {
+			sin, cos := math.Sincos(pair.Rotation)
+			ball.Vx, ball.Vy = ball.Vx*cos-ball.Vy*sin, ball.Vx*sin+ball.Vy*cos
+		}
+		ball.portalExit = to
+		return
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBallTeleportsThroughPortal(t *testing.T) {
+	setFlag(t, portalsEnabled, true)
+	setFlag(t, portalRotation, 90)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	if len(room.Portals) != 1 {
+		t.Fatalf("room has %d portal pairs, want 1", len(room.Portals))
+	}
+	a, b := room.Portals[0].A, room.Portals[0].B
+
+	ball := &room.Ball
+	*ball = Ball{X: a.X - a.Radius - 2, Y: a.Y, Vx: 5}
+	room.stepBall(ball)
+	// Entered portal A 3 pixels in, so it leaves B 3 pixels in, turned a quarter
+	want := Ball{X: b.X - b.Radius + 3, Y: b.Y, Vx: 0, Vy: 5}
+	if !near(ball.X, want.X) || !near(ball.Y, want.Y) || !near(ball.Vx, want.Vx) || !near(ball.Vy, want.Vy) {
+		t.Fatalf("ball at (%v, %v) moving (%v, %v), want at (%v, %v) moving (%v, %v)", ball.X, ball.Y, ball.Vx, ball.Vy, want.X, want.Y, want.Vx, want.Vy)
+	}
+
+	// Still inside B, it isn't sent straight back
+	room.stepBall(ball)
+	if !near(ball.X, want.X) || !near(ball.Y, want.Y+5) {
+		t.Fatalf("ball at (%v, %v) a tick after teleporting, want (%v, %v)", ball.X, ball.Y, want.X, want.Y+5)
+	}
+}
+
+// near reports whether two coordinates agree to within rounding error
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
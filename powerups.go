@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Power-ups, enabled per room by whoever creates it with /ws?powerups=true.
+// Now and then one appears on the court; the ball collects it by passing
+// through, for the player who last hit the ball.
+const (
+	EnlargePowerUp = "enlarge" // The collector's paddle grows
+	ShrinkPowerUp  = "shrink"  // The collector's opponent's paddle shrinks
+	SpeedPowerUp   = "speed"   // The ball speeds up at once
+)
+
+// Power-up kinds in the order they are drawn from
+var powerUpKinds = []string{EnlargePowerUp, ShrinkPowerUp, SpeedPowerUp}
+
+// Power-up options
+var (
+	powerUpInterval = flag.Duration("powerup-interval", 10*time.Second, "time between power-ups appearing in rooms that enable them")
+	powerUpDuration = flag.Duration("powerup-duration", 5*time.Second, "how long paddle size power-ups last")
+)
+
+// Power-up tuning
+const (
+	maxPowerUps   = 2    // On the court at once
+	powerUpRadius = 20   // Pixels
+	enlargeScale  = 1.5  // Paddle length under EnlargeEffect
+	shrinkScale   = 0.6  // Paddle length under ShrinkEffect
+	speedBoost    = 1.25 // Ball speed multiplier from SpeedPowerUp
+)
+
+// PowerUp is a power-up waiting on the court
+type PowerUp struct {
+	Kind   string  `json:"kind"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+}
+
+// updatePowerUps spawns power-ups on schedule and applies any the ball has
+// reached. Only classic rooms with power-ups enabled have them. Caller must
+// hold room lock.
+func (room *Room) updatePowerUps(now time.Time) {
+	if !room.PowerUpsEnabled || room.Mode != ClassicMode {
+		return
+	}
+	if room.nextPowerUp.IsZero() {
+		room.nextPowerUp = now.Add(*powerUpInterval)
+	}
+	if now.After(room.nextPowerUp) && len(room.PowerUps) < maxPowerUps {
+		room.spawnPowerUp()
+		room.nextPowerUp = now.Add(*powerUpInterval)
+	}
+
+	// Nobody to credit until someone returns the ball
+	if room.lastHitter == "" {
+		return
+	}
+	waiting := room.PowerUps[:0]
+powerUps:
+	for _, p := range room.PowerUps {
+		for _, ball := range room.balls() {
+			if math.Hypot(ball.X-p.X, ball.Y-p.Y) <= p.Radius+BallRadius {
+				room.applyPowerUp(p.Kind, room.lastHitter, ball)
+				continue powerUps
+			}
+		}
+		waiting = append(waiting, p)
+	}
+	room.PowerUps = waiting
+}
+
+// spawnPowerUp puts a random power-up somewhere in the middle half of the
+// court, away from the paddles. Caller must hold room lock.
+func (room *Room) spawnPowerUp() {
+	width, height := float64(room.Config.Width), float64(room.Config.Height)
+	p := PowerUp{
+		Kind:   powerUpKinds[room.rand.Intn(len(powerUpKinds))],
+		X:      width/4 + room.rand.Float64()*width/2,
+		Y:      powerUpRadius + room.rand.Float64()*(height-2*powerUpRadius),
+		Radius: powerUpRadius,
+	}
+	room.PowerUps = append(room.PowerUps, p)
+	slog.Info("Power-up appeared", "room", room.ID, "kind", p.Kind)
+}
+
+// applyPowerUp gives the effect of a power-up a ball collected. Caller must
+// hold room lock.
+func (room *Room) applyPowerUp(kind, collector string, ball *Ball) {
+	slog.Info("Power-up collected", "room", room.ID, "kind", kind, "player", collector)
+	switch kind {
+	case EnlargePowerUp:
+		room.applyEffect(EnlargeEffect, collector, *powerUpDuration)
+	case ShrinkPowerUp:
+		room.applyEffect(ShrinkEffect, opponent(collector), *powerUpDuration)
+	case SpeedPowerUp:
+		speed := math.Hypot(ball.Vx, ball.Vy)
+		faster := math.Min(speed*speedBoost, math.Max(speed, room.speedCap()))
+		ball.Vx *= faster / speed
+		ball.Vy *= faster / speed
+	}
+}
+
+// paddleLength returns an upright paddle's length with size effects applied.
+// Caller must hold room lock.
+func (room *Room) paddleLength(side string) float64 {
+	length := float64(room.Config.PaddleHeight)
+	if room.hasEffect(EnlargeEffect, side) {
+		length *= enlargeScale
+	}
+	if room.hasEffect(ShrinkEffect, side) {
+		length *= shrinkScale
+	}
+	return length
+}
+
+// paddleSpan returns where an upright paddle's top edge is and how long it
+// is. A resized paddle keeps its center and is cut off at the top and bottom
+// of the board. Caller must hold room lock.
+func (room *Room) paddleSpan(side string) (top, length float64) {
+	y := room.PanYLeft
+	if side == "right" {
+		y = room.PanYRight
+	}
+	length = room.paddleLength(side)
+	top = float64(y) + (float64(room.Config.PaddleHeight)-length)/2
+	bottom := math.Min(top+length, float64(room.Config.Height))
+	top = math.Max(top, 0)
+	return top, bottom - top
+}
+
+// resizedPaddles returns the lengths of paddles power-ups have changed, by
+// side, or nil. Caller must hold room lock.
+func (room *Room) resizedPaddles() map[string]int {
+	var lengths map[string]int
+	for _, side := range quadSides[:2] {
+		if length := int(room.paddleLength(side)); length != room.Config.PaddleHeight {
+			if lengths == nil {
+				lengths = make(map[string]int)
+			}
+			lengths[side] = length
+		}
+	}
+	return lengths
+}
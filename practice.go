@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+)
+
+// Side that is a solid wall in practice mode. The lone player holds the other.
+var practiceWall = flag.String("practice-wall", "right", `side that is a solid wall in practice mode: "left" or "right"`)
+
+// walled reports whether a side is the practice wall, which has no paddle and
+// sends the ball straight back instead of conceding. Caller must hold room
+// lock.
+func (room *Room) walled(side string) bool {
+	return room.Mode == PracticeMode && side == *practiceWall
+}
+
+// countsReturns reports whether the room counts a lone player's consecutive
+// returns, in survival and practice modes. Caller must hold room lock.
+func (room *Room) countsReturns() bool {
+	return room.Mode == SurvivalMode || room.Mode == PracticeMode
+}
+
+// endStreak ends a practice player's run of returns when they miss. The
+// point still goes against them. Caller must hold room lock.
+func (room *Room) endStreak() {
+	room.BestRally = max(room.BestRally, room.Rally)
+	slog.Debug("Practice streak ended", "room", room.ID, "returns", room.Rally, "best", room.BestRally)
+	room.Rally = 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import "slices"
+
+// Presence is who is connected to a room. It goes out with every assign and
+// to everyone whenever a player or spectator joins or leaves, so clients can
+// tell whether they have an opponent yet.
+type Presence struct {
+	Players    int               `json:"players"`      // Connections holding a paddle
+	Sides      []string          `json:"sides"`        // Paddles held by a connected player
+	AI         bool              `json:"ai,omitempty"` // The AI plays the free paddle
+	Spectators int               `json:"spectators"`
+	Colors     map[string]string `json:"colors"` // Paddle colors by side, the same for every client
+}
+
+// presence reports who is in the room. Must be called without holding room
+// lock.
+func (room *Room) presence() *Presence {
+	room.Lock()
+	defer room.Unlock()
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+
+	p := &Presence{Sides: []string{}, AI: room.soloAI != "", Spectators: len(room.spectators), Colors: room.paddleColors()}
+	for _, role := range room.clients {
+		p.Sides = append(p.Sides, role)
+	}
+	p.Players = len(p.Sides)
+	slices.Sort(p.Sides)
+	return p
+}
+
+// broadcastPresence tells everyone who is in the room. Must be called without
+// holding room lock.
+func (room *Room) broadcastPresence() {
+	room.broadcast(Message{Type: PresenceMsg, Presence: room.presence()})
+}
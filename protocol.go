@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message schema versions, negotiated as WebSocket subprotocols. A client
+// lists the versions it understands in Sec-WebSocket-Protocol and the server
+// picks the newest it also speaks. Clients that ask for none get the latest,
+// as every client did before versions existed.
+//
+// pong.v1 is the schema from before scores, timestamps and deltas: its
+// clients get full updates every frame, never deltas or binary frames, and
+// only the fields of messageV1.
+const (
+	ProtocolV1 = "pong.v1"
+	ProtocolV2 = "pong.v2"
+)
+
+// Versions the server speaks, newest first
+var protocols = []string{ProtocolV2, ProtocolV1}
+
+// supportedProtocol reports whether a handshake asks for no version or for
+// at least one the server speaks
+func supportedProtocol(r *http.Request) bool {
+	requested := websocket.Subprotocols(r)
+	return len(requested) == 0 || slices.ContainsFunc(requested, func(p string) bool {
+		return slices.Contains(protocols, p)
+	})
+}
+
+// messageEncoder turns a message into a frame for one schema version
+type messageEncoder func(Message) ([]byte, error)
+
+// encoderFor returns the encoder for a negotiated version, "" being the latest
+func encoderFor(version string) messageEncoder {
+	if version == ProtocolV1 {
+		return encodeV1
+	}
+	return encodeLatest
+}
+
+// encodeLatest encodes a message with every field
+func encodeLatest(msg Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// messageV1 is a message as pong.v1 clients know it: the fields Message had
+// before scores, timestamps and deltas, and none added since. See Message for
+// what each one carries.
+type messageV1 struct {
+	Type           string           `json:"type"`
+	Player         string           `json:"player,omitempty"`
+	Y              *int             `json:"y,omitempty"`
+	X              *int             `json:"x,omitempty"`
+	Direction      *int             `json:"direction,omitempty"`
+	LeftY          int              `json:"leftY,omitempty"`
+	RightY         int              `json:"rightY,omitempty"`
+	TopX           int              `json:"topX,omitempty"`
+	BottomX        int              `json:"bottomX,omitempty"`
+	BallX          float64          `json:"ballX,omitempty"`
+	BallY          float64          `json:"ballY,omitempty"`
+	Balls          []Vector         `json:"balls,omitempty"`
+	BallColor      string           `json:"ballColor,omitempty"`
+	Speed          float64          `json:"speed,omitempty"`
+	Winner         string           `json:"winner,omitempty"`
+	Eliminated     []string         `json:"eliminated,omitempty"`
+	Names          *SideNames       `json:"names,omitempty"`
+	Presence       *Presence        `json:"presence,omitempty"`
+	Config         *Config          `json:"config,omitempty"`
+	Token          string           `json:"token,omitempty"`
+	Name           string           `json:"name,omitempty"`
+	Mode           string           `json:"mode,omitempty"`
+	Visibility     string           `json:"visibility,omitempty"`
+	Paused         bool             `json:"paused,omitempty"`
+	Subscription   string           `json:"subscription,omitempty"`
+	Option         string           `json:"option,omitempty"`
+	Text           string           `json:"text,omitempty"`
+	Emote          string           `json:"emote,omitempty"`
+	Rally          *int             `json:"rally,omitempty"`
+	BestRally      int              `json:"bestRally,omitempty"`
+	Color          string           `json:"color,omitempty"`
+	Tally          map[string]int   `json:"tally,omitempty"`
+	Physics        string           `json:"physics,omitempty"`
+	PendingPhysics string           `json:"pendingPhysics,omitempty"`
+	Host           string           `json:"host,omitempty"`
+	State          string           `json:"state,omitempty"`
+	Position       int              `json:"position,omitempty"`
+	RetryAfter     int              `json:"retryAfter,omitempty"`
+	Options        []string         `json:"options,omitempty"`
+	Count          int              `json:"count,omitempty"`
+	Code           string           `json:"code,omitempty"`
+	Hint           string           `json:"hint,omitempty"`
+	Effects        []Effect         `json:"effects,omitempty"`
+	Band           *Band            `json:"band,omitempty"`
+	Portals        []PortalPair     `json:"portals,omitempty"`
+	PowerUps       []PowerUp        `json:"powerUps,omitempty"`
+	Lengths        map[string]int   `json:"lengths,omitempty"`
+	Wind           *Vector          `json:"wind,omitempty"`
+	Serving        string           `json:"serving,omitempty"`
+	Angle          *float64         `json:"angle,omitempty"`
+	Reach          map[string]Range `json:"reach,omitempty"`
+}
+
+// encodeV1 encodes a message with only the fields pong.v1 clients know
+func encodeV1(msg Message) ([]byte, error) {
+	return json.Marshal(messageV1{
+		Type:           msg.Type,
+		Player:         msg.Player,
+		Y:              msg.Y,
+		X:              msg.X,
+		Direction:      msg.Direction,
+		LeftY:          msg.LeftY,
+		RightY:         msg.RightY,
+		TopX:           msg.TopX,
+		BottomX:        msg.BottomX,
+		BallX:          msg.BallX,
+		BallY:          msg.BallY,
+		Balls:          msg.Balls,
+		BallColor:      msg.BallColor,
+		Speed:          msg.Speed,
+		Winner:         msg.Winner,
+		Eliminated:     msg.Eliminated,
+		Names:          msg.Names,
+		Presence:       msg.Presence,
+		Config:         msg.Config,
+		Token:          msg.Token,
+		Name:           msg.Name,
+		Mode:           msg.Mode,
+		Visibility:     msg.Visibility,
+		Paused:         msg.Paused,
+		Subscription:   msg.Subscription,
+		Option:         msg.Option,
+		Text:           msg.Text,
+		Emote:          msg.Emote,
+		Rally:          msg.Rally,
+		BestRally:      msg.BestRally,
+		Color:          msg.Color,
+		Tally:          msg.Tally,
+		Physics:        msg.Physics,
+		PendingPhysics: msg.PendingPhysics,
+		Host:           msg.Host,
+		State:          msg.State,
+		Position:       msg.Position,
+		RetryAfter:     msg.RetryAfter,
+		Options:        msg.Options,
+		Count:          msg.Count,
+		Code:           msg.Code,
+		Hint:           msg.Hint,
+		Effects:        msg.Effects,
+		Band:           msg.Band,
+		Portals:        msg.Portals,
+		PowerUps:       msg.PowerUps,
+		Lengths:        msg.Lengths,
+		Wind:           msg.Wind,
+		Serving:        msg.Serving,
+		Angle:          msg.Angle,
+		Reach:          msg.Reach,
+	})
+}
+
+// legacy reports whether a connection negotiated pong.v1. Caller must hold
+// clientsMutex.
+func (room *Room) legacy(conn *websocket.Conn) bool {
+	s, ok := room.senders[conn]
+	return ok && s.version == ProtocolV1
+}
+
+// writeLegacy sends a message to every pong.v1 player and spectator,
+// skipping those for which skip returns true. writeAll leaves them out.
+// Caller must hold clientsMutex.
+func (room *Room) writeLegacy(msg Message, action string, skip func(*websocket.Conn) bool) {
+	var frame []byte
+	write := func(client *websocket.Conn) {
+		if !room.legacy(client) || skip != nil && skip(client) {
+			return
+		}
+		if frame == nil {
+			var err error
+			if frame, err = encodeV1(msg); err != nil {
+				return
+			}
+		}
+		if err := room.sendTo(client, websocket.TextMessage, frame); err != nil {
+			room.dropClient(client, err, action)
+		}
+	}
+	for client := range room.clients {
+		write(client)
+	}
+	for client := range room.spectators {
+		write(client)
+	}
+}
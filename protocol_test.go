@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// jsonKeys returns the keys of a struct type's JSON encoding
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		keys[name] = true
+	}
+	return keys
+}
+
+func TestEncodeV1OnlyKnownFields(t *testing.T) {
+	allowed := jsonKeys(reflect.TypeOf(messageV1{}))
+	for _, key := range []string{"scoreLeft", "scoreRight", "series", "seq", "inputSeq", "acks", "time", "t", "timeLeft", "suddenDeath", "velocity"} {
+		if allowed[key] {
+			t.Errorf("pong.v1 frames may carry %q, which v1 clients don't know", key)
+		}
+	}
+
+	// Every field of Message set, whenever it was added
+	r := rand.New(rand.NewSource(1))
+	for range 20 {
+		v, ok := quick.Value(reflect.TypeOf(Message{}), r)
+		if !ok {
+			t.Fatal("can't generate a Message")
+		}
+		msg := v.Interface().(Message)
+		frame, err := encodeV1(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(frame, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for key := range fields {
+			if !allowed[key] {
+				t.Fatalf("pong.v1 frame carries %q", key)
+			}
+		}
+		if want, _ := json.Marshal(msg.Type); string(fields["type"]) != string(want) {
+			t.Fatalf("pong.v1 frame type %s, want %s", fields["type"], want)
+		}
+	}
+}
+
+func TestEncoderFor(t *testing.T) {
+	msg := Message{Type: UpdateMessage, LeftY: 5, ScoreLeft: 2}
+	for _, tt := range []struct {
+		version   string
+		wantScore bool
+	}{
+		{version: "", wantScore: true},
+		{version: ProtocolV2, wantScore: true},
+		{version: ProtocolV1, wantScore: false},
+	} {
+		frame, err := encoderFor(tt.version)(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(frame), `"scoreLeft"`); got != tt.wantScore {
+			t.Errorf("version %q frame %s has score %v, want %v", tt.version, frame, got, tt.wantScore)
+		}
+	}
+}
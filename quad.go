@@ -0,0 +1,191 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+)
+
+// Game modes, chosen by whoever creates a room with /ws?mode=<mode>. In quad
+// mode four paddles defend the four walls; letting the ball past your wall
+// eliminates you and the last player standing wins. In survival mode one
+// player keeps a rally going against the AI for as long as they can. In
+// practice mode one player warms up against a solid wall.
+const (
+	ClassicMode  = "classic"
+	QuadMode     = "quad"
+	SurvivalMode = "survival"
+	PracticeMode = "practice"
+)
+
+// Paddles in quad mode; classic plays only the first two. Top and bottom
+// paddles lie flat: PaddleHeight long along the wall and PaddleWidth deep.
+var quadSides = []string{"left", "right", "top", "bottom"}
+
+// sides returns the paddles a room's mode plays with
+func (room *Room) sides() []string {
+	if room.Mode == QuadMode {
+		return quadSides
+	}
+	return quadSides[:2]
+}
+
+// horizontal reports whether a side's paddle moves along X
+func horizontal(side string) bool {
+	return side == "top" || side == "bottom"
+}
+
+// MaxPaddleX is the furthest right a flat paddle's left edge may go
+func (c Config) MaxPaddleX() int {
+	return c.Width - c.PaddleHeight
+}
+
+// clampXPosition keeps a flat paddle's X on the board
+func (c Config) clampXPosition(x int) int {
+	return max(0, min(x, c.MaxPaddleX()))
+}
+
+// paddleX returns a pointer to a flat paddle's X, or nil for an upright one.
+// Caller must hold room lock.
+func (room *Room) paddleX(side string) *int {
+	switch side {
+	case "top":
+		return &room.PanXTop
+	case "bottom":
+		return &room.PanXBottom
+	}
+	return nil
+}
+
+// startQuadMatch clears the eliminations from the last match, counts sides
+// nobody plays as eliminated so their walls are solid, and serves. Caller
+// must hold room lock.
+func (room *Room) startQuadMatch() {
+	room.assignMutex.Lock()
+	playing := make(map[string]bool, len(room.assignedPlayers))
+	for _, side := range room.assignedPlayers {
+		playing[side] = true
+	}
+	room.assignMutex.Unlock()
+
+	room.Eliminated = nil
+	for _, side := range quadSides {
+		if !playing[side] {
+			room.Eliminated = append(room.Eliminated, side)
+		}
+	}
+	room.serveQuad()
+}
+
+// serveQuad puts the ball in the middle and sends it off diagonally toward a
+// random corner. Caller must hold room lock.
+func (room *Room) serveQuad() {
+	room.endRally()
+	room.speedHits = 0
+	room.SpeedTier = 0
+	room.Ball.X = float64(room.Config.Width / 2)
+	room.Ball.Y = float64(room.Config.Height / 2)
+
+	angle := 20 + room.rand.Float64()*50 + 90*float64(room.rand.Intn(4))
+	speed := math.Min(math.Hypot(room.BallSpeed, room.BallSpeed), room.speedCap())
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	room.Ball.Vx = speed * cos
+	room.Ball.Vy = speed * sin
+}
+
+// stepQuadBall moves the ball one tick in quad mode, returning it off live
+// paddles and the walls of eliminated sides. It returns the side whose wall
+// the ball got past, or "". Caller must hold room lock.
+func (room *Room) stepQuadBall() string {
+	ball := &room.Ball
+	ball.X += ball.Vx
+	ball.Y += ball.Vy
+
+	width, height := float64(room.Config.Width), float64(room.Config.Height)
+	depth, length := float64(room.Config.PaddleWidth), float64(room.Config.PaddleHeight)
+	for _, side := range quadSides {
+		// The axis across this wall, where the wall is on it and which way is
+		// back into the court
+		pos, vel, wall, inward := &ball.X, &ball.Vx, 0.0, 1.0
+		switch side {
+		case "right":
+			wall, inward = width, -1
+		case "top":
+			pos, vel = &ball.Y, &ball.Vy
+		case "bottom":
+			pos, vel, wall, inward = &ball.Y, &ball.Vy, height, -1
+		}
+		if *vel*inward >= 0 {
+			continue // Moving away from this wall
+		}
+		dist := (*pos - wall) * inward
+
+		if slices.Contains(room.Eliminated, side) {
+			if dist <= BallRadius {
+				*pos = wall + inward*BallRadius
+				*vel = -*vel
+			}
+			continue
+		}
+
+		var hit bool
+		switch side {
+		case "left":
+			hit = ballHitsRect(ball, 0, float64(room.PanYLeft), depth, length)
+		case "right":
+			hit = ballHitsRect(ball, width-depth, float64(room.PanYRight), depth, length)
+		case "top":
+			hit = ballHitsRect(ball, float64(room.PanXTop), 0, length, depth)
+		case "bottom":
+			hit = ballHitsRect(ball, float64(room.PanXBottom), height-depth, length, depth)
+		}
+		switch {
+		case hit:
+			*pos = wall + inward*(depth+BallRadius)
+			*vel = -*vel
+			speed := math.Hypot(ball.Vx, ball.Vy)
+			faster := speed * room.rampSpeed()
+			scale := math.Min(faster, room.speedCap()) / speed
+			ball.Vx *= scale
+			ball.Vy *= scale
+			room.RallyHits++
+			room.emitEvent(Event{Type: HitEvent, Player: side})
+		case dist < 0:
+			return side
+		}
+	}
+	return ""
+}
+
+// eliminate knocks a side out of a quad match. It returns the result once a
+// single player is left, otherwise the ball is served again. Caller must hold
+// room lock.
+func (room *Room) eliminate(side string) *MatchResult {
+	if room.State != StatePlaying || slices.Contains(room.Eliminated, side) {
+		return nil
+	}
+	room.Eliminated = append(room.Eliminated, side)
+	slog.Info("Player eliminated", "room", room.ID, "player", side)
+
+	var standing []string
+	for _, s := range quadSides {
+		if !slices.Contains(room.Eliminated, s) {
+			standing = append(standing, s)
+		}
+	}
+	if len(standing) > 1 {
+		room.serveQuad()
+		return nil
+	}
+
+	result := &MatchResult{Duration: room.now().Sub(room.matchStarted), Players: room.matchPlayers, Names: room.Names}
+	if len(standing) == 1 {
+		result.Winner = standing[0]
+	}
+	room.matchStarted = time.Time{}
+	room.matchPlayers = nil
+	room.State = StateGameOver
+	room.serveQuad()
+	return result
+}
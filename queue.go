@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Longest the waiting queue may grow. 0 disables queueing: a full room turns
+// new connections away as before.
+var maxQueue = flag.Int("max-queue", 0, "max connections waiting for a paddle (0 disables the queue)")
+
+// How often a waiting connection is told its position, which also detects dead connections
+const queueUpdateInterval = 5 * time.Second
+
+// queueEntry is a connection waiting for a paddle
+type queueEntry struct {
+	conn   *websocket.Conn
+	joined time.Time
+	ready  chan string   // Receives the assigned paddle
+	moved  chan struct{} // Signaled when the entry moves up the queue
+}
+
+// enqueue adds a connection to the back of the queue, returning its entry and
+// 1-based position, or nil if the queue is full
+func (room *Room) enqueue(conn *websocket.Conn) (*queueEntry, int) {
+	room.queueMutex.Lock()
+	defer room.queueMutex.Unlock()
+
+	if len(room.waitQueue) >= *maxQueue {
+		return nil, 0
+	}
+	entry := &queueEntry{conn: conn, joined: time.Now(), ready: make(chan string, 1), moved: make(chan struct{}, 1)}
+	room.waitQueue = append(room.waitQueue, entry)
+	return entry, len(room.waitQueue)
+}
+
+// leaveQueue removes an entry, reporting false if it had already been promoted
+func (room *Room) leaveQueue(entry *queueEntry) bool {
+	room.queueMutex.Lock()
+	defer room.queueMutex.Unlock()
+
+	for i, e := range room.waitQueue {
+		if e == entry {
+			room.waitQueue = append(room.waitQueue[:i], room.waitQueue[i+1:]...)
+			room.queueShifted(i)
+			return true
+		}
+	}
+	return false
+}
+
+// queuePosition returns an entry's 1-based position, or 0 if it's no longer queued
+func (room *Room) queuePosition(entry *queueEntry) int {
+	room.queueMutex.Lock()
+	defer room.queueMutex.Unlock()
+
+	for i, e := range room.waitQueue {
+		if e == entry {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// promoteFromQueue hands a freed paddle to the longest-waiting connection
+func (room *Room) promoteFromQueue() {
+	room.queueMutex.Lock()
+	defer room.queueMutex.Unlock()
+
+	// A paddle held for a dropped player isn't up for grabs
+	if room.slotHeld() {
+		return
+	}
+	for len(room.waitQueue) > 0 {
+		entry := room.waitQueue[0]
+		player, err := room.assignPlayer(entry.conn, "")
+		if err != nil || player == "none" {
+			return
+		}
+		room.waitQueue = room.waitQueue[1:]
+		room.queueShifted(0)
+
+		wait := time.Since(entry.joined)
+		room.matched++
+		room.totalWait += wait
+		slog.Info("Promoted from queue", "room", room.ID, "remote_addr", entry.conn.RemoteAddr().String(), "player", player, "wait", wait.Round(time.Millisecond))
+		entry.ready <- player
+	}
+}
+
+// queueShifted tells the entries from index i on that they moved up, without
+// waiting for their writers to catch up. Caller must hold queueMutex.
+func (room *Room) queueShifted(i int) {
+	for _, e := range room.waitQueue[i:] {
+		select {
+		case e.moved <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// queueReader reads a queued connection, so a client that leaves is dequeued
+// at once rather than on the next position update. A pending read can't be
+// cut short without breaking the connection, so once promoted the read loop
+// goes on reading through next.
+type queueReader struct {
+	frames chan []byte
+	done   chan struct{} // Closed once reading stops
+	err    error         // Why reading stopped, set before done is closed
+}
+
+// startQueueReader reads ws until a read fails or ctx is done
+func startQueueReader(ctx context.Context, ws *websocket.Conn) *queueReader {
+	qr := &queueReader{frames: make(chan []byte), done: make(chan struct{})}
+	go func() {
+		defer close(qr.done)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				qr.err = err
+				return
+			}
+			select {
+			case qr.frames <- data:
+			case <-ctx.Done():
+				qr.err = ctx.Err()
+				return
+			}
+		}
+	}()
+	return qr
+}
+
+// next returns the next frame read, or the error reading stopped with
+func (qr *queueReader) next() ([]byte, error) {
+	select {
+	case data := <-qr.frames:
+		return data, nil
+	case <-qr.done:
+		return nil, qr.err
+	}
+}
+
+// waitInQueue holds a connection until a paddle frees up. It returns the
+// assigned paddle and the reader the connection must go on being read
+// through, or "" if the queue is full or the connection dropped.
+func (room *Room) waitInQueue(ctx context.Context, ws *websocket.Conn, out *sender) (string, *queueReader) {
+	entry, position := room.enqueue(ws)
+	if entry == nil {
+		slog.Warn("Queue full, rejecting connection", "room", room.ID, "remote_addr", ws.RemoteAddr().String())
+		out.sendJSON(Message{
+			Type:       ErrorMessage,
+			Code:       QueueFullError,
+			RetryAfter: roomFullRetryAfter,
+			Hint:       "The game and its waiting queue are full. Please try again later.",
+		})
+		out.closeWith(CloseQueueFull, "queue full")
+		return "", nil
+	}
+	slog.Info("Queued connection", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "position", position)
+
+	// Pings and the reads that see their pongs go on past promotion
+	startKeepalive(ctx, ws)
+	reader := startQueueReader(ctx, ws)
+	leave := func(err error) {
+		slog.Info("Queued connection dropped", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		if !room.leaveQueue(entry) {
+			// Promoted while failing; give the paddle back
+			room.releasePlayer(ws)
+			room.promoteFromQueue()
+		}
+	}
+
+	// A paddle may have freed up between assignment and enqueueing
+	room.promoteFromQueue()
+
+	ticker := time.NewTicker(queueUpdateInterval)
+	defer ticker.Stop()
+	for {
+		if position > 0 {
+			if err := out.sendJSON(Message{Type: QueuedMessage, Position: position}); err != nil {
+				leave(err)
+				return "", nil
+			}
+		}
+
+		select {
+		case player := <-entry.ready:
+			return player, reader
+		case <-reader.frames:
+			// Nothing a queued client says matters yet, and its position
+			// hasn't changed
+			position = 0
+		case <-reader.done:
+			leave(reader.err)
+			return "", nil
+		case <-entry.moved:
+			position = room.queuePosition(entry)
+		case <-ticker.C:
+			position = room.queuePosition(entry)
+		}
+	}
+}
+
+// releasePlayer frees the paddle assigned to a connection. Its reconnection
+// token stays valid only while the paddle is held for it.
+func (room *Room) releasePlayer(conn *websocket.Conn) {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+
+	side, ok := room.unassign(conn)
+	if ok && side != room.heldSide {
+		delete(room.tokens, side)
+	}
+}
+
+// QueueStats summarizes the waiting queue
+type QueueStats struct {
+	Depth         int     `json:"depth"`
+	MaxDepth      int     `json:"maxDepth"`
+	Matched       int     `json:"matched"`
+	AverageWaitMs float64 `json:"averageWaitMs"`
+}
+
+// handleQueueStats serves a room's queue depth and average wait time as JSON
+func (s *Server) handleQueueStats(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Query().Get("room")
+	if roomID == "" {
+		roomID = DefaultRoomID
+	}
+	room := s.lookupRoom(roomID)
+	if room == nil {
+		http.Error(w, "no such room", http.StatusNotFound)
+		return
+	}
+
+	room.queueMutex.Lock()
+	stats := QueueStats{Depth: len(room.waitQueue), MaxDepth: *maxQueue, Matched: room.matched}
+	if room.matched > 0 {
+		stats.AverageWaitMs = float64(room.totalWait.Milliseconds()) / float64(room.matched)
+	}
+	room.queueMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		slog.Error("Error encoding queue stats", "err", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// offeredQueue connects a client to a full room and checks it may queue
+func offeredQueue(t *testing.T, ts *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn := dialTest(t, ts, "")
+	if full := readUntil(t, conn, ErrorMessage); full.Code != RoomFullError || !slices.Contains(full.Options, JoinQueue) {
+		t.Fatalf("got %+v, want room_full offering the queue", full)
+	}
+	return conn
+}
+
+func TestQueueFullRejectsAndWaitIsMeasured(t *testing.T) {
+	setFlag(t, maxQueue, 1)
+	setFlag(t, maxConnsPerIP, 0)
+	setFlag(t, reconnectGrace, 0)
+	_, ts := newTestServer(t, testConfig(t))
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	readUntil(t, dialTest(t, ts, ""), AssignMessage)
+
+	// Both are offered the one place in the queue; the second to take it is turned away
+	waiting := offeredQueue(t, ts)
+	late := offeredQueue(t, ts)
+	if err := waiting.WriteJSON(Message{Type: JoinMessage, Option: JoinQueue}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readUntil(t, waiting, QueuedMessage); msg.Position != 1 {
+		t.Fatalf("queued at %d, want 1", msg.Position)
+	}
+	if err := late.WriteJSON(Message{Type: JoinMessage, Option: JoinQueue}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readUntil(t, late, ErrorMessage); msg.Code != QueueFullError {
+		t.Fatalf("got code %q joining a full queue, want %q", msg.Code, QueueFullError)
+	}
+	_, err := readMessage(late)
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseQueueFull {
+		t.Fatalf("connection ended with %v, want close code %d", err, CloseQueueFull)
+	}
+
+	// Once full, the queue isn't offered at all
+	conn := dialTest(t, ts, "")
+	if full := readUntil(t, conn, ErrorMessage); slices.Contains(full.Options, JoinQueue) {
+		t.Fatalf("full queue offered in %v", full.Options)
+	}
+
+	const wait = 50 * time.Millisecond
+	time.Sleep(wait)
+	first.Close()
+	if msg := readUntil(t, waiting, AssignMessage); msg.Player != "left" {
+		t.Fatalf("queued connection got %q, want the freed left paddle", msg.Player)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats QueueStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Depth != 0 || stats.MaxDepth != 1 || stats.Matched != 1 || stats.AverageWaitMs < float64(wait.Milliseconds()) {
+		t.Fatalf("queue stats %+v, want an empty queue of 1 that matched one player after %v or more", stats, wait)
+	}
+}
+
+func TestClosedQueuedClientLeavesQueue(t *testing.T) {
+	setFlag(t, maxQueue, 2)
+	setFlag(t, maxConnsPerIP, 0)
+	setFlag(t, reconnectGrace, 0)
+	setFlag(t, maxPaddleJump, 0)
+	cfg := testConfig(t)
+	_, ts := newTestServer(t, cfg)
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	readUntil(t, dialTest(t, ts, ""), AssignMessage)
+
+	var waiters []*websocket.Conn
+	for want := 1; want <= 2; want++ {
+		conn := offeredQueue(t, ts)
+		if err := conn.WriteJSON(Message{Type: JoinMessage, Option: JoinQueue}); err != nil {
+			t.Fatal(err)
+		}
+		if msg := readUntil(t, conn, QueuedMessage); msg.Position != want {
+			t.Fatalf("queued at %d, want %d", msg.Position, want)
+		}
+		waiters = append(waiters, conn)
+	}
+
+	// Well before the next position update could notice it writing
+	waiters[0].Close()
+	next := waiters[1]
+	next.SetReadDeadline(time.Now().Add(queueUpdateInterval / 2))
+	var msg Message
+	if err := next.ReadJSON(&msg); err != nil || msg.Type != QueuedMessage || msg.Position != 1 {
+		t.Fatalf("after the first waiter left got %q at %d (%v), want queued at 1", msg.Type, msg.Position, err)
+	}
+
+	// Promoted, it's read as any player is
+	first.Close()
+	if msg := readUntil(t, next, AssignMessage); msg.Player != "left" {
+		t.Fatalf("queued connection got %q, want the freed left paddle", msg.Player)
+	}
+	y := cfg.MaxPaddleY() / 3
+	if err := next.WriteJSON(Message{Type: MoveMessage, Y: &y}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		msg := readUntil(t, next, UpdateMessage)
+		if msg.LeftY == y {
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Most paddle moves a connection may send per second. The game loop only
+// samples paddles at its tick rate, so dropping the excess loses nothing.
+const maxMovesPerSecond = 120
+
+// Moves a connection may send back to back before the rate applies
+const moveBurst = 10
+
+// rateLimiter is a per-connection token bucket
+type rateLimiter struct {
+	rate    float64 // Tokens added per second
+	burst   float64 // Bucket size
+	tokens  float64
+	last    time.Time
+	dropped int // Messages dropped since the last one allowed
+}
+
+// newRateLimiter returns a limiter with a full bucket
+func newRateLimiter(now time.Time, rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// newMoveLimiter returns a limiter for paddle moves
+func newMoveLimiter(now time.Time) *rateLimiter {
+	return newRateLimiter(now, maxMovesPerSecond, moveBurst)
+}
+
+// allow reports whether a message arriving at now may be applied
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens < 1 {
+		l.dropped++
+		return false
+	}
+	l.tokens--
+	l.dropped = 0
+	return true
+}
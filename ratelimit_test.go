@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMoveLimiterBurst(t *testing.T) {
+	now := time.Now()
+	l := newMoveLimiter(now)
+	allowed := 0
+	for range 100 {
+		if l.allow(now) {
+			allowed++
+		}
+	}
+	if allowed != moveBurst {
+		t.Fatalf("allowed %d of a 100-move burst, want %d", allowed, moveBurst)
+	}
+	if l.dropped != 100-moveBurst {
+		t.Fatalf("counted %d dropped, want %d", l.dropped, 100-moveBurst)
+	}
+}
+
+func TestMoveLimiterRate(t *testing.T) {
+	start := time.Now()
+	l := newMoveLimiter(start)
+	// A move every millisecond for a second, far over the limit
+	allowed := 0
+	for i := range 1000 {
+		if l.allow(start.Add(time.Duration(i) * time.Millisecond)) {
+			allowed++
+		}
+	}
+	want := moveBurst + maxMovesPerSecond
+	if math.Abs(float64(allowed-want)) > 1 {
+		t.Fatalf("allowed %d moves in a second, want about %d", allowed, want)
+	}
+	// Keeping under the rate, nothing is dropped
+	now := start.Add(2 * time.Second)
+	for i := range 100 {
+		if !l.allow(now.Add(time.Duration(i) * time.Second / maxMovesPerSecond)) {
+			t.Fatalf("move %d at the limit dropped", i)
+		}
+	}
+}
+
+func TestMoveFloodThrottled(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	_, ts := newTestServer(t, testConfig(t))
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	// Each move asks for a new position, so every one let through is logged
+	logs := captureLogs(t)
+	start := time.Now()
+	for y := 1; y <= 100; y++ {
+		if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &y}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Frames are handled in order, so once the chat is back every move has been
+	if err := conn.WriteJSON(Message{Type: ChatMessage, Text: "done"}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, conn, ChatMessage)
+	elapsed := time.Since(start)
+	applied := strings.Count(logs.String(), "Updated paddle")
+	if most := moveBurst + int(elapsed.Seconds()*maxMovesPerSecond) + 1; applied > most {
+		t.Fatalf("%d of 100 moves sent in %v applied, want at most %d", applied, elapsed, most)
+	}
+	if applied < moveBurst {
+		t.Fatalf("only %d moves of the burst applied, want at least %d", applied, moveBurst)
+	}
+	if !strings.Contains(logs.String(), "Throttling moves") {
+		t.Fatal("throttled connection not logged")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Range is an inclusive span of paddle Y positions
+type Range struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// parseReach reads an optional comfort range from the "reachMin"/"reachMax"
+// query params. Either bound may be omitted and must lie within 0-maxY.
+// Returns nil if neither is set.
+func parseReach(q url.Values, maxY int) (*Range, error) {
+	if q.Get("reachMin") == "" && q.Get("reachMax") == "" {
+		return nil, nil
+	}
+
+	reach := Range{Min: 0, Max: maxY}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"reachMin", &reach.Min}, {"reachMax", &reach.Max}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", p.name, v)
+		}
+		*p.dst = n
+	}
+
+	if reach.Min < 0 || reach.Max > maxY || reach.Min >= reach.Max {
+		return nil, fmt.Errorf("reach %d-%d must be an increasing range within 0-%d", reach.Min, reach.Max, maxY)
+	}
+	return &reach, nil
+}
+
+// setReach restricts (or with nil, frees) a player's paddle, moving it into
+// the range if it starts outside. Caller must hold room lock.
+func (room *Room) setReach(player string, reach *Range) {
+	if reach == nil {
+		delete(room.Reach, player)
+		return
+	}
+	if room.Reach == nil {
+		room.Reach = make(map[string]Range)
+	}
+	room.Reach[player] = *reach
+	switch player {
+	case "left":
+		room.PanYLeft = room.clampToReach(player, room.PanYLeft)
+	case "right":
+		room.PanYRight = room.clampToReach(player, room.PanYRight)
+	}
+}
+
+// clampToReach keeps y inside the player's comfort range, if any. Caller must hold room lock.
+func (room *Room) clampToReach(player string, y int) int {
+	reach, ok := room.Reach[player]
+	if !ok {
+		return y
+	}
+	if y < reach.Min {
+		return reach.Min
+	}
+	if y > reach.Max {
+		return reach.Max
+	}
+	return y
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestParseReach(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *Range
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "reachMin=100&reachMax=300", want: &Range{Min: 100, Max: 300}},
+		{query: "reachMin=100", want: &Range{Min: 100, Max: 500}},
+		{query: "reachMax=300", want: &Range{Min: 0, Max: 300}},
+		{query: "reachMin=x", wantErr: true},
+		{query: "reachMin=-1", wantErr: true},
+		{query: "reachMax=501", wantErr: true},
+		{query: "reachMin=300&reachMax=300", wantErr: true},
+		{query: "reachMin=300&reachMax=100", wantErr: true},
+	}
+	for _, tt := range tests {
+		q, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseReach(q, 500)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReach(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("parseReach(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPaddleStaysInReach(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	cfg := testConfig(t)
+	s, ts := newTestServer(t, cfg)
+	// A band clear of where the paddle starts
+	reach := Range{Min: cfg.MaxPaddleY()/2 + 50, Max: cfg.MaxPaddleY() - 50}
+	conn := dialTest(t, ts, "?reachMin="+strconv.Itoa(reach.Min)+"&reachMax="+strconv.Itoa(reach.Max))
+	readUntil(t, conn, AssignMessage)
+
+	room := s.lookupRoom(DefaultRoomID)
+	paddle := func() int {
+		room.Lock()
+		defer room.Unlock()
+		return room.PanYLeft
+	}
+	if y := paddle(); y < reach.Min || y > reach.Max {
+		t.Fatalf("paddle started at %d, outside its reach %d-%d", y, reach.Min, reach.Max)
+	}
+	if msg := readUntil(t, conn, UpdateMessage); msg.Reach["left"] != reach {
+		t.Fatalf("update carried reach %v, want %v for left", msg.Reach, reach)
+	}
+
+	for _, target := range []int{0, cfg.MaxPaddleY(), reach.Min - 1, reach.Max + 1} {
+		if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &target}); err != nil {
+			t.Fatal(err)
+		}
+		want := min(max(target, reach.Min), reach.Max)
+		waitFor(t, "the paddle to move", func() bool { return paddle() == want })
+	}
+
+	// Held keys stop at the edge of the band too
+	for _, dir := range []int{-1, 1} {
+		if err := conn.WriteJSON(Message{Type: MoveMessage, Direction: &dir}); err != nil {
+			t.Fatal(err)
+		}
+		want := reach.Min
+		if dir > 0 {
+			want = reach.Max
+		}
+		waitFor(t, "the paddle to reach the edge of its band", func() bool { return paddle() == want })
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Match recording and replay. With -record-dir set, every JSON frame a room
+// sends to all its clients, plus each assign, is appended to a file per
+// match, <dir>/<room>-<unix ms>.jsonl, one RecordedFrame per line. A file
+// starts with the first frame after the previous match ended and closes
+// after its gameover frame. Binary position frames aren't recorded; the JSON
+// delta sent alongside them carries the same positions.
+//
+// With -replay the server runs no rooms: each client connecting to /ws is
+// made a spectator and streamed the recording from the start at its original
+// cadence. Recorded assigns are replayed as spectator assigns so the client
+// picks up the board geometry without taking a paddle.
+var (
+	recordDir  = flag.String("record-dir", "", "record each match's frames to a file in this directory (empty disables recording)")
+	replayFile = flag.String("replay", "", "stream this recorded match to every client instead of running games")
+)
+
+// RecordedFrame is one line of a match recording
+type RecordedFrame struct {
+	Time  int64           `json:"time"` // Unix milliseconds when the frame went out
+	Frame json.RawMessage `json:"frame"`
+}
+
+// matchRecording is the file a room is recording its current match to
+type matchRecording struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// record appends a frame to the current match's recording, starting one if
+// needed. Caller must hold clientsMutex.
+func (room *Room) record(frame []byte) {
+	if *recordDir == "" {
+		return
+	}
+	if room.recording == nil {
+		name := filepath.Join(*recordDir, fmt.Sprintf("%s-%d.jsonl", room.ID, time.Now().UnixMilli()))
+		f, err := os.Create(name)
+		if err != nil {
+			slog.Error("Error starting match recording", "room", room.ID, "err", err)
+			return
+		}
+		w := bufio.NewWriter(f)
+		room.recording = &matchRecording{file: f, w: w, enc: json.NewEncoder(w)}
+		slog.Info("Recording match", "room", room.ID, "file", name)
+	}
+	if err := room.recording.enc.Encode(RecordedFrame{Time: time.Now().UnixMilli(), Frame: frame}); err != nil {
+		slog.Error("Error recording frame", "room", room.ID, "err", err)
+	}
+}
+
+// recordMessage records a frame sent to a single client
+func (room *Room) recordMessage(msg Message) {
+	if *recordDir == "" {
+		return
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Error marshaling recorded message", "room", room.ID, "msg_type", msg.Type, "err", err)
+		return
+	}
+	room.clientsMutex.Lock()
+	room.record(msgBytes)
+	room.clientsMutex.Unlock()
+}
+
+// finishRecording closes the current match's recording, if any; the next
+// recorded frame starts a new one
+func (room *Room) finishRecording() {
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	room.closeRecording()
+}
+
+// closeRecording is finishRecording for a caller that holds clientsMutex
+func (room *Room) closeRecording() {
+	rec := room.recording
+	if rec == nil {
+		return
+	}
+	room.recording = nil
+	if err := rec.w.Flush(); err != nil {
+		slog.Error("Error flushing match recording", "room", room.ID, "err", err)
+	}
+	if err := rec.file.Close(); err != nil {
+		slog.Error("Error closing match recording", "room", room.ID, "err", err)
+	}
+}
+
+// loadRecording reads a match recording, rewriting its assigns for spectators
+func loadRecording(path string) ([]RecordedFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var frames []RecordedFrame
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var rf RecordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &rf); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var msg Message
+		if err := json.Unmarshal(rf.Frame, &msg); err != nil {
+			return nil, fmt.Errorf("line %d: frame: %w", line, err)
+		}
+		if msg.Type == AssignMessage {
+			msg.Player, msg.Token = SpectatorRole, ""
+			if rf.Frame, err = json.Marshal(msg); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		frames = append(frames, rf)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s has no frames", path)
+	}
+	return frames, nil
+}
+
+// replayHandler streams a recording to each WebSocket client that connects
+func (s *Server) replayHandler(frames []RecordedFrame) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ws, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			slog.Warn("Upgrade error", "remote_addr", r.RemoteAddr, "err", err)
+			return
+		}
+		defer ws.Close()
+		slog.Info("Replaying match", "remote_addr", ws.RemoteAddr().String(), "frames", len(frames))
+
+		// Nothing the client sends matters, but reading notices it leaving
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := ws.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		start := time.Now()
+		for _, rf := range frames {
+			due := start.Add(time.Duration(rf.Time-frames[0].Time) * time.Millisecond)
+			select {
+			case <-time.After(time.Until(due)):
+			case <-gone:
+				return
+			}
+			if err := ws.WriteMessage(websocket.TextMessage, rf.Frame); err != nil {
+				slog.Info("Replay client dropped", "remote_addr", ws.RemoteAddr().String(), "err", err)
+				return
+			}
+		}
+		slog.Info("Replay finished", "remote_addr", ws.RemoteAddr().String())
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay finished"))
+		select {
+		case <-gone:
+		case <-time.After(time.Second):
+		}
+	}
+}
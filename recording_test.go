@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRecordedMatchReplaysFrameForFrame(t *testing.T) {
+	dir := t.TempDir()
+	setFlag(t, recordDir, dir)
+	setFlag(t, winningScore, 1)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+	left := dialTest(t, ts, "")
+	readUntil(t, left, AssignMessage)
+	right := dialTest(t, ts, "")
+	readUntil(t, right, AssignMessage)
+	playOut(t, cfg, GameOverMsg, left, right)
+
+	// The match's file is the first; the next match starts another
+	var frames []RecordedFrame
+	waitFor(t, "the recording to finish", func() bool {
+		files, err := filepath.Glob(filepath.Join(dir, DefaultRoomID+"-*.jsonl"))
+		if err != nil || len(files) == 0 {
+			return false
+		}
+		slices.Sort(files)
+		frames, err = loadRecording(files[0])
+		return err == nil && frameType(t, frames[len(frames)-1]) == GameOverMsg
+	})
+	var types []string
+	for _, rf := range frames {
+		types = append(types, frameType(t, rf))
+	}
+	for _, want := range []string{AssignMessage, UpdateMessage, GameOverMsg} {
+		if !slices.Contains(types, want) {
+			t.Fatalf("recording has no %s frame: %v", want, types)
+		}
+	}
+
+	replaying := NewServer(cfg, nil)
+	rs := httptest.NewServer(replaying.Handler(frames))
+	t.Cleanup(rs.Close)
+	watcher := dialTest(t, rs, "")
+	for i, want := range frames {
+		_, got, err := watcher.ReadMessage()
+		if err != nil {
+			t.Fatalf("replay ended after %d of %d frames: %v", i, len(frames), err)
+		}
+		if !bytes.Equal(got, want.Frame) {
+			t.Fatalf("replayed frame %d is %s, recorded %s", i, got, want.Frame)
+		}
+	}
+	_, _, err := watcher.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormalClosure {
+		t.Fatalf("replay ended with %v, want a normal close", err)
+	}
+}
+
+func TestReplayedAssignsMakeSpectators(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "match.jsonl")
+	assign, err := json.Marshal(RecordedFrame{Time: 1, Frame: json.RawMessage(`{"type":"assign","player":"left","token":"secret"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(assign, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := loadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg Message
+	if err := json.Unmarshal(frames[0].Frame, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Player != SpectatorRole || msg.Token != "" {
+		t.Fatalf("replayed assign gives player %q token %q, want a spectator with no token", msg.Player, msg.Token)
+	}
+}
+
+// frameType returns the type of a recorded frame
+func frameType(t *testing.T, rf RecordedFrame) string {
+	t.Helper()
+	var msg Message
+	if err := json.Unmarshal(rf.Frame, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg.Type
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Replay tool options. When -replay-snapshot is set the server doesn't start;
+// it loads the snapshot, steps the physics and prints the trajectory instead.
+// Gameplay flags (-color-bounce, -scoring-band, ...) must match the run the
+// snapshot was taken from.
+var (
+	replaySnapshot = flag.String("replay-snapshot", "", "load a game snapshot from this file, print its trajectory and exit")
+	replayTicks    = flag.Int("replay-ticks", 600, "number of ticks to run with -replay-snapshot")
+)
+
+// Snapshot is everything needed to reproduce the physics from a point in a match
+type Snapshot struct {
+	Seed       int64        `json:"seed"`
+	LeftY      int          `json:"leftY"`
+	RightY     int          `json:"rightY"`
+	Ball       Ball         `json:"ball"`
+	Wind       Vector       `json:"wind"`
+	Portals    []PortalPair `json:"portals,omitempty"`
+	RallyHits  int          `json:"rallyHits"`
+	Serving    string       `json:"serving,omitempty"`
+	ServeAngle float64      `json:"serveAngle,omitempty"`
+	BallSpeed  float64      `json:"ballSpeed,omitempty"`
+	ScoreLeft  int          `json:"scoreLeft"`
+	ScoreRight int          `json:"scoreRight"`
+}
+
+// takeSnapshot captures the current state. The game RNG is reseeded with the
+// recorded seed so that every draw after the snapshot can be reproduced.
+// Caller must hold room lock.
+func (room *Room) takeSnapshot() Snapshot {
+	next := room.rand.Int63()
+	room.rand.Seed(next)
+	return Snapshot{
+		Seed:       next,
+		LeftY:      room.PanYLeft,
+		RightY:     room.PanYRight,
+		Ball:       room.Ball,
+		Wind:       room.Wind,
+		Portals:    room.Portals,
+		RallyHits:  room.RallyHits,
+		Serving:    room.Serving,
+		ServeAngle: room.ServeAngle,
+		BallSpeed:  room.BallSpeed,
+		ScoreLeft:  room.ScoreLeft,
+		ScoreRight: room.ScoreRight,
+	}
+}
+
+// loadSnapshot replaces the current state with a snapshot. Caller must hold room lock.
+func (room *Room) loadSnapshot(s Snapshot) {
+	room.rand.Seed(s.Seed)
+	room.PanYLeft = s.LeftY
+	room.PanYRight = s.RightY
+	room.Ball = s.Ball
+	room.Wind = s.Wind
+	room.Portals = s.Portals
+	room.RallyHits = s.RallyHits
+	room.Serving = s.Serving
+	room.ServeAngle = s.ServeAngle
+	room.ScoreLeft = s.ScoreLeft
+	room.ScoreRight = s.ScoreRight
+	if s.BallSpeed != 0 {
+		room.BallSpeed = s.BallSpeed
+	}
+	room.Ball.portalExit = nil
+}
+
+// replayFromSnapshot runs the physics forward from a snapshot file, writing one line per tick
+func replayFromSnapshot(cfg Config, path string, ticks int, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parsing snapshot: %w", err)
+	}
+
+	// A stepped room on a private server, so serve delays pass in ticks
+	room := NewServer(cfg, nil).NewSteppedRoom("replay", RoomOptions{Mode: ClassicMode, Balls: 1})
+	room.Lock()
+	defer room.Unlock()
+
+	room.loadSnapshot(snap)
+	for tick := 1; tick <= ticks; tick++ {
+		if room.Serving != "" {
+			fmt.Fprintf(out, "%d serve held by %s\n", tick, room.Serving)
+			break
+		}
+		room.simTime = room.simTime.Add(room.Config.TickInterval())
+		if room.servePending(room.simTime) {
+			fmt.Fprintf(out, "%d serve delayed\n", tick)
+			continue
+		}
+		conceded := room.stepBall(&room.Ball)
+		b := room.Ball
+		fmt.Fprintf(out, "%d ball=(%.3f, %.3f) v=(%.3f, %.3f) leftY=%d rightY=%d",
+			tick, b.X, b.Y, b.Vx, b.Vy, room.PanYLeft, room.PanYRight)
+		if conceded != "" {
+			fmt.Fprintf(out, " out=%s", conceded)
+			if result := room.scorePoint(conceded); result != nil {
+				fmt.Fprintf(out, " winner=%s", result.Winner)
+			}
+			fmt.Fprintf(out, " score=%d-%d", room.ScoreLeft, room.ScoreRight)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Room used when a client doesn't ask for one
+const DefaultRoomID = "default"
+
+// Longest room ID accepted from the query string
+const maxRoomIDLength = 32
+
+// Room is one independent match with its own state, clients and game loop.
+// The embedded GameState mutex guards the game; the other collections have
+// their own locks. When several are needed they are taken in the order:
+// room lock, queueMutex, assignMutex, clientsMutex, votesMutex.
+type Room struct {
+	GameState
+
+	ID string
+
+	clients      map[*websocket.Conn]string
+	clientsMutex sync.Mutex
+	// Connections watching a full room; guarded by clientsMutex
+	spectators map[*websocket.Conn]struct{}
+	// Clients that asked for something other than the full feed; guarded by clientsMutex
+	subscriptions map[*websocket.Conn]string
+	// Clients that asked for binary position frames; guarded by clientsMutex
+	binaryClients map[*websocket.Conn]struct{}
+	// Writer for every connection in the room, queued ones included; guarded by clientsMutex
+	senders map[*websocket.Conn]*sender
+	// Match being recorded with -record-dir; guarded by clientsMutex
+	recording *matchRecording
+
+	// Assign players to paddles, and the order each connection got its paddle in
+	assignedPlayers map[*websocket.Conn]string
+	assignedAt      map[*websocket.Conn]uint64
+	assignments     uint64
+	assignMutex     sync.Mutex
+	// Paddle held for a player who dropped mid-match; guarded by assignMutex
+	heldSide string
+	// Reconnection token issued for each paddle; guarded by assignMutex
+	tokens map[string]string
+
+	// One vote per connection for the next match's ball speed
+	votes      map[*websocket.Conn]string
+	votesMutex sync.Mutex
+
+	// Waiting connections in arrival order, plus wait-time stats for matched players
+	waitQueue  []*queueEntry
+	queueMutex sync.Mutex
+	matched    int
+	totalWait  time.Duration
+
+	// Solo opponent level requested by a lone player, and how far off the
+	// ball each AI paddle is aiming; guarded by the room lock
+	soloAI string
+	aiAims map[string]aiAim
+
+	// Game RNG; only used while holding the room lock
+	rand *rand.Rand
+
+	// Last game state broadcast and when it went out; guarded by the room lock
+	lastState     []byte
+	lastStateSent time.Time
+	// Previous update and frames since the last full one, for deltas; guarded by the room lock
+	lastUpdate      Message
+	lastRest        []byte
+	framesSinceFull int
+	// Ball velocity clients were last told, see velocityUpdate; guarded by the room lock
+	sentVelocity Vector
+
+	// Physics and broadcast loop clocks, stopped by closing done
+	ticker          *time.Ticker
+	broadcastTicker *time.Ticker
+	done            chan struct{}
+	// Last phase seen by the game loop, for logging transitions
+	lastPhase string
+	// Stepped rooms run on simulated time, advanced only by Step; guarded by
+	// the room lock
+	stepped bool
+	simTime time.Time
+	// An administrator froze the room, see freeze.go: only Step advances
+	// simTime. clockLag is how far the room's clock has fallen behind the
+	// wall clock while frozen. Guarded by the room lock.
+	frozen   bool
+	clockLag time.Duration
+
+	// Server the room belongs to
+	server *Server
+	// Connections and queued players using the room; guarded by the server's roomsMutex
+	members int
+}
+
+// RoomOptions are chosen by whoever creates a room; later arrivals get the
+// room as it is
+type RoomOptions struct {
+	Mode     string
+	PowerUps bool
+	Balls    int        // Balls in play; quad mode ignores it
+	Names    SideNames  // Side names; empty uses -left-name and -right-name
+	Rand     *rand.Rand // Source of every random game event; nil seeds one from -seed or the clock
+}
+
+// newRoom creates a room with the initial game state. Its loop isn't started.
+func (s *Server) newRoom(id string, opts RoomOptions) *Room {
+	mode := opts.Mode
+	room := &Room{
+		server: s,
+		GameState: GameState{
+			Mode:            mode,
+			PowerUpsEnabled: opts.PowerUps,
+			Names:           cmp.Or(opts.Names, SideNames{Left: *leftName, Right: *rightName}),
+			PanYLeft:        s.config.MaxPaddleY() / 2,
+			PanYRight:       s.config.MaxPaddleY() / 2,
+			Ball: Ball{
+				X: s.config.BallStartX,
+				Y: s.config.BallStartY,
+			},
+			BallSpeed: s.config.BallSpeed,
+			Physics:   "normal",
+			Wind:      Vector{X: *windX, Y: *windY},
+			Config:    s.config,
+			State:     StateWaiting,
+		},
+		ID:              id,
+		clients:         make(map[*websocket.Conn]string),
+		spectators:      make(map[*websocket.Conn]struct{}),
+		subscriptions:   make(map[*websocket.Conn]string),
+		binaryClients:   make(map[*websocket.Conn]struct{}),
+		senders:         make(map[*websocket.Conn]*sender),
+		assignedPlayers: make(map[*websocket.Conn]string),
+		assignedAt:      make(map[*websocket.Conn]uint64),
+		tokens:          make(map[string]string),
+		votes:           make(map[*websocket.Conn]string),
+		rand:            opts.Rand,
+		lastPhase:       PhaseSimulating,
+	}
+	if room.rand == nil {
+		room.rand = rand.New(rand.NewSource(roomSeed(id)))
+	}
+	if *portalsEnabled {
+		room.Portals = defaultPortals(room.Config)
+	}
+	if mode == QuadMode {
+		room.PanXTop = s.config.MaxPaddleX() / 2
+		room.PanXBottom = s.config.MaxPaddleX() / 2
+		room.serveQuad()
+		return room
+	}
+	room.receiver = "right"
+	room.serveToward(room.receiver)
+	for i := 1; i < opts.Balls; i++ {
+		room.ExtraBalls = append(room.ExtraBalls, Ball{})
+		extra := &room.ExtraBalls[len(room.ExtraBalls)-1]
+		room.spotBall(extra)
+		room.sendBallToward(extra, "left")
+	}
+	room.holdServe("left")
+	return room
+}
+
+// validateRoomID checks a room ID from the query string
+func validateRoomID(id string) error {
+	if len(id) > maxRoomIDLength {
+		return fmt.Errorf("room ID longer than %d characters", maxRoomIDLength)
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("room ID %q may only contain letters, digits, '-' and '_'", id)
+		}
+	}
+	return nil
+}
+
+// acquireRoom returns the named room, creating and starting it with the given
+// options if needed, and counts the caller as a member until releaseRoom
+func (s *Server) acquireRoom(id string, opts RoomOptions) *Room {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room, ok := s.rooms[id]
+	if !ok {
+		room = s.newRoom(id, opts)
+		s.rooms[id] = room
+		room.start()
+		roomsGauge.Inc()
+		slog.Info("Created room", "room", id, "mode", opts.Mode, "powerups", opts.PowerUps, "balls", opts.Balls)
+	}
+	room.members++
+	return room
+}
+
+// releaseRoom drops a member and shuts the room down when the last one leaves
+func (s *Server) releaseRoom(room *Room) {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	room.members--
+	if room.members > 0 {
+		return
+	}
+	delete(s.rooms, room.ID)
+	room.stop()
+	roomsGauge.Dec()
+	slog.Info("Closed empty room", "room", room.ID)
+}
+
+// lookupRoom returns an existing room without creating it
+func (s *Server) lookupRoom(id string) *Room {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+	return s.rooms[id]
+}
+
+// activeRooms returns a snapshot of the server's rooms
+func (s *Server) activeRooms() []*Room {
+	s.roomsMutex.Lock()
+	defer s.roomsMutex.Unlock()
+
+	active := make([]*Room, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		active = append(active, room)
+	}
+	return active
+}
+
+// start runs the room's physics and broadcast loops
+func (room *Room) start() {
+	room.ticker = time.NewTicker(room.Config.TickInterval())
+	room.broadcastTicker = time.NewTicker(room.Config.BroadcastInterval())
+	room.done = make(chan struct{})
+	go room.gameLoop()
+	go room.broadcastLoop()
+}
+
+// stop ends the room's loops
+func (room *Room) stop() {
+	room.ticker.Stop()
+	room.broadcastTicker.Stop()
+	close(room.done)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// What a client turned away from a full room may do instead, sent in the
+// options of a room_full error and chosen with a join message
+const (
+	JoinQueue    = "queue"    // Wait for a paddle
+	JoinSpectate = "spectate" // Watch the match
+)
+
+// Seconds a client turned away from a full room is told to wait before
+// trying again. Matches rarely end sooner.
+const roomFullRetryAfter = 30
+
+// How long a client offered options has to choose before it is disconnected
+const joinChoiceWait = 30 * time.Second
+
+// fullRoomOptions lists what a connection that got no paddle may do instead
+func (room *Room) fullRoomOptions() []string {
+	var options []string
+	room.queueMutex.Lock()
+	if len(room.waitQueue) < *maxQueue {
+		options = append(options, JoinQueue)
+	}
+	room.queueMutex.Unlock()
+
+	room.clientsMutex.Lock()
+	if len(room.spectators) < *maxSpectators {
+		options = append(options, JoinSpectate)
+	}
+	room.clientsMutex.Unlock()
+	return options
+}
+
+// handleRoomFull tells a connection that got no paddle that the room is full,
+// with what it may do instead, and waits for it to choose. It returns the
+// connection's role: a paddle won from the queue, SpectatorRole, or "" if
+// the connection should be closed. A paddle won from the queue comes with
+// the reader the connection is read through from then on.
+func (room *Room) handleRoomFull(ctx context.Context, ws *websocket.Conn, out *sender) (string, *queueReader) {
+	options := room.fullRoomOptions()
+	msg := Message{
+		Type:       ErrorMessage,
+		Code:       RoomFullError,
+		Player:     "none", // For clients that predate codes
+		RetryAfter: roomFullRetryAfter,
+		Options:    options,
+		Hint:       "Both paddles are taken. Please try again later.",
+	}
+	if len(options) == 0 {
+		slog.Info("Room full, rejecting connection", "room", room.ID, "remote_addr", ws.RemoteAddr().String())
+		out.sendJSON(msg)
+		out.closeWith(CloseRoomFull, "room full")
+		return "", nil
+	}
+	room.queueMutex.Lock()
+	if len(room.waitQueue) < *maxQueue {
+		msg.Position = len(room.waitQueue) + 1
+	}
+	room.queueMutex.Unlock()
+	msg.Hint = "Both paddles are taken. Join the queue or watch, or try again later."
+	if err := out.sendJSON(msg); err != nil {
+		return "", nil
+	}
+
+	choice, err := readJoinChoice(ws, options)
+	if err != nil {
+		slog.Info("No choice from connection turned away from full room", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "err", err)
+		out.closeWith(CloseRoomFull, "room full")
+		return "", nil
+	}
+	slog.Info("Connection chose how to wait for a paddle", "room", room.ID, "remote_addr", ws.RemoteAddr().String(), "option", choice)
+	switch choice {
+	case JoinQueue:
+		return room.waitInQueue(ctx, ws, out)
+	case JoinSpectate:
+		if room.addSpectator(ws) {
+			return SpectatorRole, nil
+		}
+	}
+	// Filled up while the client chose
+	msg.Options, msg.Position = nil, 0
+	msg.Hint = "Both paddles are taken and there's no room to watch. Please try again later."
+	out.sendJSON(msg)
+	out.closeWith(CloseRoomFull, "room full")
+	return "", nil
+}
+
+// readJoinChoice waits for a join message picking one of the options,
+// skipping any other frame, even one that isn't JSON. Only a read error or
+// running out of time to choose gives up.
+func readJoinChoice(ws *websocket.Conn, options []string) (string, error) {
+	ws.SetReadDeadline(time.Now().Add(joinChoiceWait))
+	defer ws.SetReadDeadline(time.Time{})
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			slog.Debug("Ignoring malformed message while waiting for a join choice", "remote_addr", ws.RemoteAddr().String(), "err", err)
+			continue
+		}
+		if msg.Type == JoinMessage && slices.Contains(options, msg.Option) {
+			return msg.Option, nil
+		}
+		slog.Debug("Ignoring message while waiting for a join choice", "remote_addr", ws.RemoteAddr().String(), "msg_type", msg.Type, "option", msg.Option)
+	}
+}
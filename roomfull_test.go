@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomFullSkipsBadFramesUntilChoice(t *testing.T) {
+	setFlag(t, maxQueue, 4)
+	_, ts := newTestServer(t, testConfig(t))
+	readUntil(t, dialTest(t, ts, ""), AssignMessage)
+	readUntil(t, dialTest(t, ts, ""), AssignMessage)
+
+	third := dialTest(t, ts, "")
+	full := readUntil(t, third, ErrorMessage)
+	if full.Code != RoomFullError || full.RetryAfter != roomFullRetryAfter || full.Position != 1 {
+		t.Fatalf("got %+v, want room_full with retryAfter %d and queue position 1", full, roomFullRetryAfter)
+	}
+	if !slices.Contains(full.Options, JoinQueue) || !slices.Contains(full.Options, JoinSpectate) {
+		t.Fatalf("options %v, want queue and spectate", full.Options)
+	}
+
+	// None of these picks an option, but none of them gives up either
+	for _, frame := range []struct {
+		kind int
+		data string
+	}{
+		{websocket.TextMessage, "not json"},
+		{websocket.BinaryMessage, "\x01\x02"},
+		{websocket.TextMessage, `{"type":"move","y":10}`},
+		{websocket.TextMessage, `{"type":"join","option":"play"}`},
+	} {
+		if err := third.WriteMessage(frame.kind, []byte(frame.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := third.WriteJSON(Message{Type: JoinMessage, Option: JoinSpectate}); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, third, UpdateMessage)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "flag"
+
+// Band is the vertical span of a back wall where a ball exit counts as a point
+type Band struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+}
+
+// Scoring options
+var scoringBand = flag.Int("scoring-band", 0, "height in pixels of the central band of each back wall where exits score (0 scores anywhere)")
+
+// scoringBandBounds returns the active scoring band, or nil if the whole wall scores
+func scoringBandBounds(cfg Config) *Band {
+	if *scoringBand <= 0 || *scoringBand >= cfg.Height {
+		return nil
+	}
+	return &Band{
+		Top:    float64(cfg.Height-*scoringBand) / 2,
+		Bottom: float64(cfg.Height+*scoringBand) / 2,
+	}
+}
+
+// inScoringBand reports whether a ball leaving at y counts as a point
+func inScoringBand(cfg Config, y float64) bool {
+	band := scoringBandBounds(cfg)
+	return band == nil || (y >= band.Top && y <= band.Bottom)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestScoringBand(t *testing.T) {
+	setFlag(t, scoringBand, 200)
+	room := newSteppedTestRoom(t)
+	cfg := room.Config
+	band := scoringBandBounds(cfg)
+	if band == nil || band.Top != float64(cfg.Height-200)/2 || band.Bottom != float64(cfg.Height+200)/2 {
+		t.Fatalf("band %+v, want 200 pixels around the middle of %d", band, cfg.Height)
+	}
+
+	tests := []struct {
+		name     string
+		y        float64
+		conceded string
+	}{
+		{"above the band bounces", band.Top - 50, ""},
+		{"inside the band scores", (band.Top + band.Bottom) / 2, "left"},
+		{"below the band bounces", band.Bottom + 50, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room.Lock()
+			defer room.Unlock()
+			// Keep the paddle well clear of the ball
+			room.PanYLeft = 0
+			if tt.y < float64(cfg.Height)/2 {
+				room.PanYLeft = cfg.MaxPaddleY()
+			}
+			ball := &room.Ball
+			*ball = Ball{X: 2, Y: tt.y, Vx: -5}
+
+			if got := room.stepBall(ball); got != tt.conceded {
+				t.Fatalf("exit at y %v conceded %q, want %q", tt.y, got, tt.conceded)
+			}
+			if tt.conceded == "" && (ball.X != 0 || ball.Vx <= 0) {
+				t.Fatalf("ball at x %v moving %v after bouncing off the back wall, want at 0 heading right", ball.X, ball.Vx)
+			}
+		})
+	}
+}
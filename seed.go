@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"hash/fnv"
+	"time"
+)
+
+// Seed for the game's random number generator, so matches can be reproduced
+var seed = flag.Int64("seed", 0, "random seed for game events (0 picks one from the clock)")
+
+// roomSeed derives a room's RNG seed. With -seed set every room is
+// reproducible; otherwise it comes from the clock.
+func roomSeed(id string) int64 {
+	if *seed == 0 {
+		return time.Now().UnixNano()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return *seed ^ int64(h.Sum64())
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// Games in a series. Above 1, matches are best-of-N series and the players
+// swap paddles between games so neither keeps a side of the board.
+var seriesLength = flag.Int("series-length", 1, "play best-of-N series, swapping sides between games (odd; 1 plays single matches)")
+
+// SeriesScore is the standing in a series
+type SeriesScore struct {
+	Length int            `json:"length"`
+	Played int            `json:"played"` // Games finished so far
+	Wins   map[string]int `json:"wins"`   // Games won by the player now on each side
+}
+
+// inSeries reports whether the room plays series. Caller must hold room lock.
+func (room *Room) inSeries() bool {
+	return *seriesLength > 1 && room.Mode == ClassicMode
+}
+
+// winSeriesGame credits a finished game to the side that won it and reports
+// whether that clinched the series. Caller must hold room lock.
+func (room *Room) winSeriesGame(winner string) bool {
+	if room.SeriesWins == nil {
+		room.SeriesWins = make(map[string]int)
+	}
+	room.SeriesWins[winner]++
+	room.seriesPlayed++
+	return room.SeriesWins[winner] > *seriesLength/2
+}
+
+// seriesScore returns the current standing. Caller must hold room lock.
+func (room *Room) seriesScore() *SeriesScore {
+	wins := map[string]int{"left": room.SeriesWins["left"], "right": room.SeriesWins["right"]}
+	return &SeriesScore{Length: *seriesLength, Played: room.seriesPlayed, Wins: wins}
+}
+
+// endSeries forgets the series so the next match starts a new one. Caller
+// must hold room lock.
+func (room *Room) endSeries() {
+	room.SeriesWins = nil
+	room.seriesPlayed = 0
+}
+
+// swapSides moves each player to the other paddle, along with everything
+// kept for them by side. Reconnection tokens move too, so a player who drops
+// gets their new paddle back. Caller must hold room lock.
+func (room *Room) swapSides() {
+	room.assignMutex.Lock()
+	for conn, side := range room.assignedPlayers {
+		room.assignedPlayers[conn] = opponent(side)
+	}
+	swapSideKeys(room.tokens)
+	room.assignMutex.Unlock()
+
+	room.clientsMutex.Lock()
+	for conn, role := range room.clients {
+		if role != SpectatorRole {
+			room.clients[conn] = opponent(role)
+		}
+	}
+	room.clientsMutex.Unlock()
+
+	// A side's name belongs to the team, which changes ends with its player
+	room.Names.Left, room.Names.Right = room.Names.Right, room.Names.Left
+	swapSideKeys(room.PlayerNames)
+	swapSideKeys(room.Colors)
+	swapSideKeys(room.matchPlayers)
+	swapSideKeys(room.SeriesWins)
+	swapSideKeys(room.Reach)
+	swapSideKeys(room.Background)
+	if room.Host != "" {
+		room.Host = opponent(room.Host)
+	}
+	// Inputs from the last game don't carry over
+	room.Directions = nil
+	room.paddleCarry = nil
+	room.lastMoveAt = nil
+	room.Acks = nil
+	room.sentAcks = nil
+	room.Effects = nil
+
+	slog.Info("Players swapped sides", "room", room.ID, "game", room.seriesPlayed+1)
+}
+
+// broadcastIntermission announces a finished game of a series that goes on,
+// telling each player the paddle they now control. Must be called without
+// holding room lock.
+func (room *Room) broadcastIntermission(result MatchResult) {
+	msg := Message{
+		Type:       IntermissionMsg,
+		Winner:     result.Winner,
+		ScoreLeft:  result.ScoreLeft,
+		ScoreRight: result.ScoreRight,
+		Series:     result.Series,
+		Hint:       "Sides have swapped: every player now controls the other paddle.",
+	}
+	room.server.stampEvent(&msg)
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Error marshaling message", "room", room.ID, "msg_type", msg.Type, "err", err)
+		return
+	}
+
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+
+	const action = "announcing intermission"
+	isPlayer := func(conn *websocket.Conn) bool {
+		role, ok := room.clients[conn]
+		return ok && role != SpectatorRole
+	}
+	for conn, role := range room.clients {
+		if role == SpectatorRole {
+			continue
+		}
+		assigned := msg
+		assigned.Player = role
+		var frame []byte
+		if room.legacy(conn) {
+			frame, err = encodeV1(assigned)
+		} else {
+			frame, err = json.Marshal(assigned)
+		}
+		if err != nil {
+			slog.Error("Error marshaling message", "room", room.ID, "msg_type", msg.Type, "err", err)
+			continue
+		}
+		if err := room.sendTo(conn, websocket.TextMessage, frame); err != nil {
+			room.dropClient(conn, err, action)
+		}
+	}
+	room.writeAll(msgBytes, action, isPlayer)
+	room.writeLegacy(msg, action, isPlayer)
+}
+
+// swapSideKeys exchanges the left and right entries of a map keyed by side
+func swapSideKeys[V any](bySide map[string]V) {
+	left, hasLeft := bySide["left"]
+	right, hasRight := bySide["right"]
+	delete(bySide, "left")
+	delete(bySide, "right")
+	if hasLeft {
+		bySide["right"] = left
+	}
+	if hasRight {
+		bySide["left"] = right
+	}
+}
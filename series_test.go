@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestIntermissionSendsNewSides(t *testing.T) {
+	setFlag(t, seriesLength, 3)
+	setFlag(t, winningScore, 1)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	second := dialTest(t, ts, "")
+	readUntil(t, second, AssignMessage)
+	watcher := dialTest(t, ts, "")
+	if err := watcher.WriteJSON(Message{Type: JoinMessage, Option: "spectate"}); err != nil {
+		t.Fatal(err)
+	}
+
+	intermissions := playOut(t, cfg, IntermissionMsg, first, second)
+	for i, want := range []string{"right", "left"} {
+		msg := intermissions[i]
+		if msg.Player != want {
+			t.Errorf("player %d told they now play %q, want %q", i+1, msg.Player, want)
+		}
+		if msg.Series == nil || msg.Series.Played != 1 {
+			t.Errorf("player %d got series %+v, want one game played", i+1, msg.Series)
+		}
+	}
+	if msg := readUntil(t, watcher, IntermissionMsg); msg.Player != "" || msg.Hint == "" {
+		t.Errorf("spectator got intermission for %q with hint %q, want no side and a hint", msg.Player, msg.Hint)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Aimed-serve mode holds the ball after each point until the serving player launches it
+var aimedServe = flag.Bool("aimed-serve", false, "hold the ball after each point and let the serving player aim and launch it")
+
+// Serve to each side in turn instead of to the side that conceded, for drills.
+// Aimed serves still go to the player who conceded.
+var alternateServe = flag.Bool("alternate-serve", false, "serve toward each side in turn instead of toward the side that conceded the point")
+
+// Pause after a point before the ball leaves the center, so the receiver can get ready
+var serveDelay = flag.Duration("serve-delay", time.Second, "pause after each point before the ball is served (0 serves at once)")
+
+// Legal serve angles in degrees either side of straight across
+const maxServeAngle = 60.0
+
+// Automatic serves leave at a random angle in this range (degrees), up or down
+const (
+	minRandomServeAngle = 15.0
+	maxRandomServeAngle = 45.0
+)
+
+// holdServe parks the ball and hands the serve to a player. Caller must hold room lock.
+func (room *Room) holdServe(player string) {
+	if !*aimedServe {
+		return
+	}
+	room.Serving = player
+	room.ServeAngle = 0
+	room.Ball.Vx = 0
+	room.Ball.Vy = 0
+	// The serving player launches it, not the delay
+	room.serveAt = time.Time{}
+}
+
+// aimServe updates the pending serve angle. Caller must hold room lock.
+func (room *Room) aimServe(player string, angle float64) {
+	if room.Serving != player {
+		slog.Debug("Ignoring aim from player without the serve", "room", room.ID, "player", player, "serving", room.Serving)
+		return
+	}
+	room.ServeAngle = math.Max(-maxServeAngle, math.Min(maxServeAngle, angle))
+}
+
+// launchServe sends the ball toward the opponent at the aimed angle. Caller must hold room lock.
+func (room *Room) launchServe(player string) {
+	if room.Serving != player {
+		slog.Debug("Ignoring serve from player without the serve", "room", room.ID, "player", player, "serving", room.Serving)
+		return
+	}
+	direction := 1.0
+	if player == "right" {
+		direction = -1
+	}
+	room.launchBall(&room.Ball, direction, room.ServeAngle)
+	room.Serving = ""
+	room.markActivity()
+	slog.Info("Player served", "room", room.ID, "player", player, "angle", room.ServeAngle)
+}
+
+// servePending reports whether the balls are still waiting out the serve
+// delay, serving them once it is over. Caller must hold room lock.
+func (room *Room) servePending(now time.Time) bool {
+	if room.serveAt.IsZero() {
+		return false
+	}
+	if now.Before(room.serveAt) {
+		return true
+	}
+	room.serveAt = time.Time{}
+	room.serveBalls()
+	return false
+}
+
+// serveBalls sends the ball toward the receiver. Extra balls go the other
+// way so both players have one to return. Caller must hold room lock.
+func (room *Room) serveBalls() {
+	room.serveToward(room.receiver)
+	for i := range room.ExtraBalls {
+		room.sendBallToward(&room.ExtraBalls[i], opponent(room.receiver))
+	}
+}
+
+// stopBalls holds every ball where it is. Caller must hold room lock.
+func (room *Room) stopBalls() {
+	for _, ball := range room.balls() {
+		ball.Vx = 0
+		ball.Vy = 0
+	}
+}
+
+// serveToward sends the ball toward the receiving side at a random angle.
+// Caller must hold room lock.
+func (room *Room) serveToward(receiver string) {
+	room.sendBallToward(&room.Ball, receiver)
+}
+
+// sendBallToward sends a ball toward the receiving side at a random angle.
+// Caller must hold room lock.
+func (room *Room) sendBallToward(ball *Ball, receiver string) {
+	direction := 1.0
+	if receiver == "left" {
+		direction = -1
+	}
+	angle := minRandomServeAngle + room.rand.Float64()*(maxRandomServeAngle-minRandomServeAngle)
+	if room.rand.Intn(2) == 0 {
+		angle = -angle
+	}
+	room.launchBall(ball, direction, angle)
+}
+
+// launchBall sets a ball moving horizontally in direction (1 right, -1
+// left) at angle degrees from straight across. The speed is always that of the
+// original diagonal serve, BallSpeed on each axis, within speedCap. Caller
+// must hold room lock.
+func (room *Room) launchBall(ball *Ball, direction, angle float64) {
+	speed := math.Min(math.Hypot(room.BallSpeed, room.BallSpeed), room.speedCap())
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	ball.Vx = direction * speed * cos
+	ball.Vy = speed * sin
+}
+
+// serveAngle returns the pending serve angle for broadcasting, or nil when the ball is in play. Caller must hold room lock.
+func (room *Room) serveAngle() *float64 {
+	if room.Serving == "" {
+		return nil
+	}
+	angle := room.ServeAngle
+	return &angle
+}
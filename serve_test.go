@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAimedServeLaunchesAtLastAim(t *testing.T) {
+	setFlag(t, aimedServe, true)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+
+	room.holdServe("left")
+	if room.Serving != "left" || room.Ball.Vx != 0 || room.Ball.Vy != 0 {
+		t.Fatalf("serve held by %q with the ball moving (%v, %v), want left with it still", room.Serving, room.Ball.Vx, room.Ball.Vy)
+	}
+	if got := room.serveAngle(); got == nil || *got != 0 {
+		t.Fatalf("broadcast aim %v, want 0", got)
+	}
+
+	room.aimServe("right", 40) // Not theirs to aim
+	room.aimServe("left", 20)
+	room.aimServe("left", 100)
+	if room.ServeAngle != maxServeAngle {
+		t.Fatalf("aim %v after asking for 100, want it clamped to %v", room.ServeAngle, maxServeAngle)
+	}
+	room.aimServe("left", -30)
+	room.launchServe("right") // Nor theirs to launch
+	if room.Serving != "left" {
+		t.Fatal("serve launched by the player without it")
+	}
+
+	room.launchServe("left")
+	if room.Serving != "" || room.serveAngle() != nil {
+		t.Fatalf("serve still held by %q after launching", room.Serving)
+	}
+	angle := math.Atan2(room.Ball.Vy, room.Ball.Vx) * 180 / math.Pi
+	if room.Ball.Vx <= 0 || math.Abs(angle-(-30)) > 1e-9 {
+		t.Fatalf("ball launched moving (%v, %v) at %v degrees, want toward the right at -30", room.Ball.Vx, room.Ball.Vy, angle)
+	}
+}
+
+func TestServeGoesToPlayerWhoConceded(t *testing.T) {
+	setFlag(t, serveDelay, 50*time.Millisecond)
+	for _, conceded := range []string{"left", "right", "left"} {
+		room := newSteppedTestRoom(t)
+		room.Lock()
+		room.scorePoint(conceded)
+		if room.Ball.Vx != 0 || room.Ball.Vy != 0 {
+			t.Fatalf("ball moving (%v, %v) straight after the point, want it held for the serve delay", room.Ball.Vx, room.Ball.Vy)
+		}
+		now := room.now()
+		if !room.servePending(now.Add(*serveDelay - time.Millisecond)) {
+			t.Fatal("ball served before the serve delay was up")
+		}
+		if room.servePending(now.Add(*serveDelay)) {
+			t.Fatal("ball still held once the serve delay was up")
+		}
+		if towardLeft := room.Ball.Vx < 0; towardLeft != (conceded == "left") {
+			t.Errorf("after %s conceded the ball was served moving (%v, %v)", conceded, room.Ball.Vx, room.Ball.Vy)
+		}
+		room.Unlock()
+	}
+}
+
+func TestAlternateServe(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	setFlag(t, alternateServe, true)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	var served []bool
+	for range 4 {
+		// Always the same side conceding, yet the serve swaps
+		room.scorePoint("left")
+		served = append(served, room.Ball.Vx < 0)
+	}
+	for i := 1; i < len(served); i++ {
+		if served[i] == served[i-1] {
+			t.Fatalf("serves went left %v, want them to alternate", served)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server owns the rooms and everything they share: the board config, the
+// upgrader, its HTTP routes, player statistics, match history, the rally of
+// the day and the event stream. Each Server is independent, so several can
+// run in one process, each behind its own Handler, without seeing each
+// other's games. Flags are process-wide tuning read as the game runs, and
+// the Prometheus metrics count across every Server.
+type Server struct {
+	config   Config
+	upgrader websocket.Upgrader
+	stats    StatsStore
+
+	// Exported events waiting to be written, nil when export is off or has
+	// been closed; eventsDone is closed once the writer has flushed them all.
+	// eventSeq is the sequence they share with relayed messages.
+	events      chan Event
+	eventsDone  chan struct{}
+	eventsMutex sync.Mutex
+	eventSeq    atomic.Uint64
+
+	// Active rooms by ID
+	rooms      map[string]*Room
+	roomsMutex sync.Mutex
+
+	// Ring buffer of the most recent matches across all rooms; historyNext is
+	// where the following entry goes once the buffer is full
+	history      []HistoryEntry
+	historyNext  int
+	historyMutex sync.Mutex
+
+	// Longest survival runs, best first, one per player
+	survivalScores []SurvivalScore
+	survivalMutex  sync.Mutex
+
+	// Best rally of the day; only one highlight is ever stored
+	bestRally      *Highlight
+	highlightMutex sync.Mutex
+
+	// Open WebSocket connections by client IP
+	connsPerIP map[string]int
+	connsMutex sync.Mutex
+
+	// Done once the server gives up on connections closing by themselves;
+	// every connection's goroutines stop with it
+	ctx              context.Context
+	closeConnections context.CancelFunc
+}
+
+// NewServer returns a server with no rooms that plays on the board cfg and
+// records player statistics in stats
+func NewServer(cfg Config, stats StatsStore) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		config:           cfg,
+		upgrader:         websocket.Upgrader{CheckOrigin: checkOrigin, Subprotocols: protocols},
+		stats:            stats,
+		rooms:            make(map[string]*Room),
+		history:          make([]HistoryEntry, 0, maxHistory),
+		connsPerIP:       make(map[string]int),
+		ctx:              ctx,
+		closeConnections: cancel,
+	}
+}
+
+// Handler returns the server's routes: the game at /ws, or a recorded match
+// streamed to every client if replay isn't nil, the HTTP API and, unless
+// disabled, the web client
+func (s *Server) Handler(replay []RecordedFrame) http.Handler {
+	mux := http.NewServeMux()
+	if replay != nil {
+		mux.HandleFunc("/ws", s.replayHandler(replay))
+	} else {
+		mux.HandleFunc("/ws", s.HandleConnections)
+	}
+	mux.HandleFunc("/api/highlights", s.handleHighlights)
+	mux.HandleFunc("/api/queue", s.handleQueueStats)
+	mux.HandleFunc("/history", s.handleHistory)
+	mux.HandleFunc("/survival", s.handleSurvival)
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/leaderboard", s.handleLeaderboard)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/admin/reset", s.handleAdminReset)
+	mux.HandleFunc("/admin/kick", s.handleAdminKick)
+	mux.HandleFunc("/admin/pause", s.handleAdminPause)
+	mux.HandleFunc("/admin/step", s.handleAdminStep)
+	mux.HandleFunc("/admin/resume", s.handleAdminResume)
+	if *stateToken != "" {
+		mux.HandleFunc("/state", s.handleState)
+	}
+	mountStatic(mux)
+	return mux
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// How long a test waits for a message before giving up
+const testTimeout = 10 * time.Second
+
+// setFlag sets a flag for the rest of the test
+func setFlag[T any](t testing.TB, flag *T, value T) {
+	t.Helper()
+	old := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = old })
+}
+
+// testConfig returns the board config the flags describe
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// newTestServer starts a Server playing on cfg behind an httptest server.
+// Both are shut down when the test ends.
+func newTestServer(t *testing.T, cfg Config) (*Server, *httptest.Server) {
+	t.Helper()
+	stats, err := openJSONStatsStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(cfg, stats)
+	ts := httptest.NewServer(s.Handler(nil))
+	t.Cleanup(func() {
+		s.closeConnections()
+		ts.Close()
+		// Rooms left behind would go on logging and recording into the next test
+		waitFor(t, "the rooms to close", func() bool { return len(s.activeRooms()) == 0 })
+		s.closeEventExport()
+		s.closeStats()
+	})
+	return s, ts
+}
+
+// dialTest connects to the test server's /ws with the given query string
+func dialTest(t *testing.T, ts *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts, query), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// wsURL is the address of the test server's /ws with the given query string
+func wsURL(ts *httptest.Server, query string) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws" + query
+}
+
+// readMessage reads the next JSON message
+func readMessage(conn *websocket.Conn) (Message, error) {
+	var msg Message
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	err := conn.ReadJSON(&msg)
+	return msg, err
+}
+
+// readUntil reads messages until one of type typ arrives and returns it
+func readUntil(t *testing.T, conn *websocket.Conn, typ string) Message {
+	t.Helper()
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", typ, err)
+		}
+		if msg.Type == typ {
+			return msg
+		}
+	}
+}
+
+// dodge keeps a player's paddle at the far end of the court from the ball
+// until a message of type until arrives, and returns it
+func dodge(conn *websocket.Conn, cfg Config, until string) (Message, error) {
+	target := -1
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			return msg, err
+		}
+		switch msg.Type {
+		case until:
+			return msg, nil
+		case UpdateMessage, DeltaMsg:
+			want := 0
+			if msg.BallY < float64(cfg.Height)/2 {
+				want = cfg.MaxPaddleY()
+			}
+			if want == target {
+				continue
+			}
+			target = want
+			if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &want}); err != nil {
+				return msg, err
+			}
+		}
+	}
+}
+
+// playOut has every player dodge the ball until a message of type until
+// arrives and returns the one each of them got, in the order of players
+func playOut(t *testing.T, cfg Config, until string, players ...*websocket.Conn) []Message {
+	t.Helper()
+	type outcome struct {
+		msg Message
+		err error
+	}
+	results := make([]chan outcome, len(players))
+	for i, conn := range players {
+		results[i] = make(chan outcome, 1)
+		go func() {
+			msg, err := dodge(conn, cfg, until)
+			results[i] <- outcome{msg, err}
+		}()
+	}
+	var msgs []Message
+	for _, result := range results {
+		r := <-result
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		msgs = append(msgs, r.msg)
+	}
+	return msgs
+}
+
+func TestServerPlaysFullGame(t *testing.T) {
+	setFlag(t, winningScore, 2)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+
+	left := dialTest(t, ts, "?name=alice")
+	if msg := readUntil(t, left, AssignMessage); msg.Player != "left" || msg.Name != "alice" {
+		t.Fatalf("first client assigned %q as %q, want left as alice", msg.Player, msg.Name)
+	}
+	right := dialTest(t, ts, "?name=bob")
+	if msg := readUntil(t, right, AssignMessage); msg.Player != "right" || msg.Name != "bob" {
+		t.Fatalf("second client assigned %q as %q, want right as bob", msg.Player, msg.Name)
+	}
+
+	gameOvers := playOut(t, cfg, GameOverMsg, left, right)
+	over := gameOvers[0]
+	if over.Winner != gameOvers[1].Winner || over.ScoreLeft != gameOvers[1].ScoreLeft || over.ScoreRight != gameOvers[1].ScoreRight {
+		t.Fatalf("players saw different results: %+v and %+v", over, gameOvers[1])
+	}
+	winnerScore, loserScore := over.ScoreLeft, over.ScoreRight
+	if over.Winner == "right" {
+		winnerScore, loserScore = loserScore, winnerScore
+	}
+	if over.Winner != "left" && over.Winner != "right" || winnerScore != 2 || loserScore >= 2 {
+		t.Fatalf("game over %+v, want a winner on 2 points", over)
+	}
+
+	resp, err := http.Get(ts.URL + "/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var history []HistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Winner != over.Winner || history[0].ScoreLeft != over.ScoreLeft || history[0].ScoreRight != over.ScoreRight {
+		t.Fatalf("history %+v, want the one match won by %s", history, over.Winner)
+	}
+}
+
+func TestServersAreIsolated(t *testing.T) {
+	cfg := testConfig(t)
+	_, ts1 := newTestServer(t, cfg)
+	small := cfg
+	small.Height = 400
+	_, ts2 := newTestServer(t, small)
+
+	first := dialTest(t, ts1, "")
+	if msg := readUntil(t, first, AssignMessage); msg.Player != "left" || msg.Config.Height != cfg.Height {
+		t.Fatalf("first server assigned %q on a %d high board, want left on %d", msg.Player, msg.Config.Height, cfg.Height)
+	}
+	// A game on one server takes no paddle on the other
+	second := dialTest(t, ts2, "")
+	if msg := readUntil(t, second, AssignMessage); msg.Player != "left" || msg.Config.Height != small.Height {
+		t.Fatalf("second server assigned %q on a %d high board, want left on %d", msg.Player, msg.Config.Height, small.Height)
+	}
+}
+
+// waitFor polls cond until it holds, failing the test if it doesn't in time
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// newSteppedTestRoom returns a seeded, stepped classic room on the board the
+// flags describe
+func newSteppedTestRoom(t *testing.T) *Room {
+	t.Helper()
+	return NewServer(testConfig(t), nil).NewSteppedRoom("test", RoomOptions{Mode: ClassicMode, Balls: 1, Rand: rand.New(rand.NewSource(1))})
+}
+
+func TestOversizedFrameClosesConnection(t *testing.T) {
+	_, ts := newTestServer(t, testConfig(t))
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	// Up to the limit is fine
+	chat := []byte(`{"type":"chat","text":"hi"}`)
+	padded := append(chat, strings.Repeat(" ", MaxMessageSize-len(chat))...)
+	if err := conn.WriteMessage(websocket.TextMessage, padded); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, conn, ChatMessage)
+
+	if err := conn.WriteMessage(websocket.TextMessage, append(padded, ' ')); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, err := readMessage(conn)
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != websocket.CloseMessageTooBig {
+				t.Fatalf("closed with %d, want %d", closeErr.Code, websocket.CloseMessageTooBig)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("connection ended with %v, want a close frame", err)
+		}
+	}
+
+	// The server cleaned up and carries on
+	next := dialTest(t, ts, "")
+	if msg := readUntil(t, next, AssignMessage); msg.Player != "left" {
+		t.Fatalf("next client assigned %q, want the freed left paddle", msg.Player)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// How long shutdown waits for rooms to empty and requests to finish
+const shutdownTimeout = 5 * time.Second
+
+// Closed once a signal-triggered shutdown has finished
+var shutdownDone = make(chan struct{})
+
+// shutdownOnSignal waits for SIGINT or SIGTERM, then tells every client the
+// server is going away, closes their connections and stops the HTTP server
+func (s *Server) shutdownOnSignal(server *http.Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	slog.Info("Shutting down", "signal", sig)
+	defer close(shutdownDone)
+	// Run first, once the rooms are done with, so their last results and
+	// events are kept
+	defer s.closeEventExport()
+	defer s.closeStats()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting new connections first so no room is created behind us
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Server shutdown", "err", err)
+	}
+	s.closeAllRooms()
+
+	// Each room's loop stops when its last connection's handler releases it
+	for {
+		s.roomsMutex.Lock()
+		remaining := len(s.rooms)
+		s.roomsMutex.Unlock()
+		if remaining == 0 {
+			slog.Info("All rooms closed")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			slog.Warn("Gave up waiting for rooms to close", "rooms", remaining)
+			s.closeConnections()
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// closeAllRooms sends the shutdown notice to every room and disconnects its
+// clients and queue
+func (s *Server) closeAllRooms() {
+	for _, room := range s.activeRooms() {
+		msg := Message{Type: ShutdownMessage, Hint: "The server is restarting. Please reconnect shortly."}
+		s.stampEvent(&msg)
+		room.broadcast(msg)
+		room.closeAllClients(websocket.CloseGoingAway, "server shutting down")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// assign gives a connection a paddle, remembering the order paddles were
+// handed out in. Caller must hold assignMutex.
+func (room *Room) assign(conn *websocket.Conn, side string) {
+	room.assignedPlayers[conn] = side
+	room.assignments++
+	room.assignedAt[conn] = room.assignments
+	playersGauge.Inc()
+}
+
+// unassign takes a connection's paddle away, reporting the paddle it held.
+// Caller must hold assignMutex.
+func (room *Room) unassign(conn *websocket.Conn) (string, bool) {
+	side, ok := room.assignedPlayers[conn]
+	if ok {
+		playersGauge.Dec()
+	}
+	delete(room.assignedPlayers, conn)
+	delete(room.assignedAt, conn)
+	return side, ok
+}
+
+// checkSoleHolder enforces that no other connection holds conn's paddle.
+// Assignment makes that impossible, so a second holder is a bug: both are
+// logged and the later of the two to be assigned loses the paddle and is
+// closed. It returns an error if that was conn. Caller must hold assignMutex.
+func (room *Room) checkSoleHolder(conn *websocket.Conn) error {
+	side, ok := room.assignedPlayers[conn]
+	if !ok {
+		return nil
+	}
+	for other, role := range room.assignedPlayers {
+		if other == conn || role != side {
+			continue
+		}
+		newer := conn
+		if room.assignedAt[other] > room.assignedAt[conn] {
+			newer = other
+		}
+		slog.Error("Two connections hold one paddle", "room", room.ID, "player", side,
+			"remote_addr", conn.RemoteAddr().String(), "other_remote_addr", other.RemoteAddr().String(),
+			"dropping", newer.RemoteAddr().String())
+		room.unassign(newer)
+		room.closeConn(newer, websocket.CloseInternalServerErr, "paddle already taken")
+		if newer == conn {
+			return fmt.Errorf("%s paddle is already held by %s", side, other.RemoteAddr())
+		}
+	}
+	return nil
+}
+
+// checkedPaddleOf returns the paddle a connection holds, or "" if none,
+// after making sure nobody else holds it too
+func (room *Room) checkedPaddleOf(conn *websocket.Conn) string {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+
+	if err := room.checkSoleHolder(conn); err != nil {
+		return ""
+	}
+	return room.assignedPlayers[conn]
+}
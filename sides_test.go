@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSimultaneousJoinsGetDistinctPaddles(t *testing.T) {
+	setFlag(t, maxConnsPerIP, 0)
+	_, ts := newTestServer(t, testConfig(t))
+	for i := range 20 {
+		query := fmt.Sprintf("?room=race%d", i)
+		roles := make([]string, 2)
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for j := range roles {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts, query), nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				t.Cleanup(func() { conn.Close() })
+				for {
+					msg, err := readMessage(conn)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if msg.Type == AssignMessage {
+						roles[j] = msg.Player
+						return
+					}
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if roles[0] == roles[1] || roles[0] == "" || roles[1] == "" {
+			t.Fatalf("round %d: simultaneous joins assigned %q and %q", i, roles[0], roles[1])
+		}
+	}
+}
+
+func TestSecondHolderOfPaddleDropped(t *testing.T) {
+	s, ts := newTestServer(t, testConfig(t))
+	first := dialTest(t, ts, "")
+	readUntil(t, first, AssignMessage)
+	second := dialTest(t, ts, "")
+	readUntil(t, second, AssignMessage)
+
+	// Simulate the bug: the newer connection ends up on the left paddle too
+	room := s.lookupRoom(DefaultRoomID)
+	room.assignMutex.Lock()
+	var older, newer *websocket.Conn
+	for conn := range room.assignedPlayers {
+		if older == nil || room.assignedAt[conn] < room.assignedAt[older] {
+			older, newer = conn, older
+		} else {
+			newer = conn
+		}
+	}
+	room.assignedPlayers[newer] = "left"
+	room.assignMutex.Unlock()
+
+	if side := room.checkedPaddleOf(newer); side != "" {
+		t.Fatalf("newer holder kept the %s paddle", side)
+	}
+	if side := room.checkedPaddleOf(older); side != "left" {
+		t.Fatalf("older holder has %q, want left", side)
+	}
+	for {
+		_, err := readMessage(second)
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != websocket.CloseInternalServerErr {
+				t.Fatalf("newer holder closed with %d, want %d", closeErr.Code, websocket.CloseInternalServerErr)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("newer holder's connection ended with %v, want a close frame", err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Role given to connections watching a full room
+const SpectatorRole = "spectator"
+
+// Spectators allowed per room. 0 turns away connections to a full room.
+var maxSpectators = flag.Int("max-spectators", 50, "spectators allowed per room once both paddles are taken (0 disables spectating)")
+
+// Goroutines a broadcast frame is queued for spectators from, once players
+// have theirs. Queueing doesn't wait on the network, each connection's writer
+// does that, so more than one only pays off for rooms with a great many
+// spectators on a server with cores to spare.
+var broadcastWorkers = flag.Int("broadcast-workers", 1, "goroutines queueing a broadcast frame for a room's spectators (1 queues for them one at a time)")
+
+// Fewest spectators worth handing a broadcast worker of their own
+const spectatorsPerWorker = 16
+
+// Message types a spectator may send; everything else, moves included, is ignored
+var spectatorMessages = map[string]bool{
+	VoteMessage:  true,
+	SubscribeMsg: true,
+	ChatMessage:  true,
+	EmoteMessage: true,
+}
+
+// addSpectator adds a connection to the room's spectators, reporting false if there's no room for it
+func (room *Room) addSpectator(conn *websocket.Conn) bool {
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+
+	if len(room.spectators) >= *maxSpectators {
+		return false
+	}
+	room.spectators[conn] = struct{}{}
+	slog.Info("Client is spectating", "room", room.ID, "remote_addr", conn.RemoteAddr().String())
+	return true
+}
+
+// writeAll sends a frame to every player and then every spectator, skipping
+// those for which skip returns true, and drops connections whose write fails.
+// pong.v1 connections are left to writeLegacy. Caller must hold clientsMutex.
+func (room *Room) writeAll(msgBytes []byte, action string, skip func(*websocket.Conn) bool) {
+	room.record(msgBytes)
+	write := func(client *websocket.Conn) error {
+		if skip != nil && skip(client) || room.legacy(client) {
+			return nil
+		}
+		return room.sendTo(client, websocket.TextMessage, msgBytes)
+	}
+	for client := range room.clients {
+		if err := write(client); err != nil {
+			room.dropClient(client, err, action)
+		}
+	}
+	for _, failed := range room.fanOut(write) {
+		room.dropClient(failed.conn, failed.err, action)
+	}
+}
+
+// failedWrite is a connection a frame couldn't be queued for
+type failedWrite struct {
+	conn *websocket.Conn
+	err  error
+}
+
+// fanOut calls write for every spectator, spread over up to broadcastWorkers
+// goroutines, and returns those it failed for. write runs concurrently, so it
+// may only read the room's connection maps. Caller must hold clientsMutex.
+func (room *Room) fanOut(write func(*websocket.Conn) error) []failedWrite {
+	var failures []failedWrite
+	workers := min(*broadcastWorkers, len(room.spectators)/spectatorsPerWorker)
+	if workers <= 1 {
+		for client := range room.spectators {
+			if err := write(client); err != nil {
+				failures = append(failures, failedWrite{client, err})
+			}
+		}
+		return failures
+	}
+
+	spectators := make([]*websocket.Conn, 0, len(room.spectators))
+	for client := range room.spectators {
+		spectators = append(spectators, client)
+	}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func(share []*websocket.Conn) {
+			defer wg.Done()
+			for _, client := range share {
+				if err := write(client); err != nil {
+					mu.Lock()
+					failures = append(failures, failedWrite{client, err})
+					mu.Unlock()
+				}
+			}
+		}(spectators[i*len(spectators)/workers : (i+1)*len(spectators)/workers])
+	}
+	wg.Wait()
+	return failures
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// addFakeSpectators puts n spectators in the room whose frames are counted
+// instead of written to a network connection
+func addFakeSpectators(tb testing.TB, room *Room, n int) *atomic.Int64 {
+	tb.Helper()
+	var received atomic.Int64
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	for range n {
+		conn := new(websocket.Conn)
+		s := &sender{frames: make(chan outFrame, sendBuffer), done: make(chan struct{})}
+		go func() {
+			defer close(s.done)
+			for range s.frames {
+				received.Add(1)
+			}
+		}()
+		room.spectators[conn] = struct{}{}
+		room.senders[conn] = s
+		tb.Cleanup(func() {
+			close(s.frames)
+			<-s.done
+		})
+	}
+	return &received
+}
+
+func TestFanOutReachesEverySpectatorOnce(t *testing.T) {
+	for _, workers := range []int{1, 2, 8, 100} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			setFlag(t, broadcastWorkers, workers)
+			room := newSteppedTestRoom(t)
+			received := addFakeSpectators(t, room, 100)
+
+			room.clientsMutex.Lock()
+			defer room.clientsMutex.Unlock()
+			var failed *websocket.Conn
+			for conn := range room.spectators {
+				failed = conn
+				break
+			}
+			var mu sync.Mutex
+			writes := make(map[*websocket.Conn]int)
+			failures := room.fanOut(func(conn *websocket.Conn) error {
+				mu.Lock()
+				defer mu.Unlock()
+				writes[conn]++
+				if conn == failed {
+					return errSendBufferFull
+				}
+				return nil
+			})
+			for conn := range room.spectators {
+				if writes[conn] != 1 {
+					t.Fatalf("spectator written %d times, want once", writes[conn])
+				}
+			}
+			if len(failures) != 1 || failures[0].conn != failed || failures[0].err != errSendBufferFull {
+				t.Fatalf("failures %+v, want the one failed spectator", failures)
+			}
+
+			room.writeAll([]byte("{}"), "broadcasting", func(conn *websocket.Conn) bool { return conn == failed })
+			waitFor(t, "the broadcast to be queued", func() bool { return received.Load() == 99 })
+		})
+	}
+}
+
+// BenchmarkBroadcast compares queueing a frame for 100 spectators one at a
+// time with spreading them over broadcast workers
+func BenchmarkBroadcast(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 6} {
+		name := "serial"
+		if workers > 1 {
+			name = fmt.Sprintf("%d workers", workers)
+		}
+		b.Run(name, func(b *testing.B) {
+			setFlag(b, broadcastWorkers, workers)
+			room := NewServer(Config{}, nil).NewSteppedRoom("bench", RoomOptions{Mode: ClassicMode, Balls: 1})
+			received := addFakeSpectators(b, room, 100)
+			frame := []byte(`{"type":"update","ballX":400,"ballY":300}`)
+			b.ResetTimer()
+			for i := range b.N {
+				// Let the spectators catch up before their buffers fill
+				if i%(sendBuffer/2) == 0 {
+					b.StopTimer()
+					for received.Load() < int64(i*100) {
+						runtime.Gosched()
+					}
+					b.StartTimer()
+				}
+				room.clientsMutex.Lock()
+				room.writeAll(frame, "broadcasting", nil)
+				room.clientsMutex.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"math"
+)
+
+// Spin ("English") from paddle motion: a paddle moving as it returns the ball
+// drags the ball along, curving its return the way the paddle was going.
+var (
+	spinFactor    = flag.Float64("spin", 0.3, "fraction of a paddle's velocity added to the ball's vertical velocity on a hit (0 disables spin)")
+	maxSpin       = flag.Float64("max-spin", 3, "most pixels per tick spin may add to the ball's vertical velocity")
+	spinAddsSpeed = flag.Bool("spin-adds-speed", false, "let spin speed the ball up instead of only changing its direction")
+)
+
+// trackPaddleVelocity records how far each upright paddle moved since the
+// last tick, however it was moved. Called once per tick after the paddles
+// move. Caller must hold room lock.
+func (room *Room) trackPaddleVelocity() {
+	positions := map[string]int{"left": room.PanYLeft, "right": room.PanYRight}
+	if room.PaddleVelocity == nil {
+		room.PaddleVelocity = make(map[string]int)
+		room.lastPaddleY = positions
+	}
+	for side, y := range positions {
+		room.PaddleVelocity[side] = y - room.lastPaddleY[side]
+	}
+	room.lastPaddleY = positions
+}
+
+// applySpin adds spin from a paddle's motion to a ball it just returned.
+// The ball keeps its speed unless spinAddsSpeed is set, and never exceeds
+// speedCap. Caller must hold room lock.
+func (room *Room) applySpin(ball *Ball, side string) {
+	spin := *spinFactor * float64(room.PaddleVelocity[side])
+	spin = math.Max(-*maxSpin, math.Min(*maxSpin, spin))
+	if spin == 0 {
+		return
+	}
+
+	speed := math.Hypot(ball.Vx, ball.Vy)
+	ball.Vy += spin
+	spun := math.Hypot(ball.Vx, ball.Vy)
+	if *spinAddsSpeed {
+		speed = math.Min(spun, math.Max(speed, room.speedCap()))
+	}
+	scale := speed / spun
+	ball.Vx *= scale
+	ball.Vy *= scale
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// returnBall hits the ball straight into the middle of the left paddle while
+// it moves at velocity pixels per tick, and returns the ball's new velocity
+func returnBall(t *testing.T, velocity int) Ball {
+	t.Helper()
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	cfg := room.Config
+	room.PanYLeft = cfg.MaxPaddleY() / 2
+	room.PaddleVelocity = map[string]int{"left": velocity}
+	room.Ball = Ball{X: float64(cfg.PaddleWidth) + BallRadius + 3, Y: float64(room.PanYLeft) + float64(cfg.PaddleHeight)/2, Vx: -5}
+	if conceded := room.stepBall(&room.Ball); conceded != "" {
+		t.Fatalf("ball got past the paddle")
+	}
+	if room.Ball.Vx <= 0 {
+		t.Fatalf("ball not returned: %+v", room.Ball)
+	}
+	return room.Ball
+}
+
+func TestSpinFromPaddleMotion(t *testing.T) {
+	setFlag(t, spinAddsSpeed, false)
+	still := returnBall(t, 0)
+	up := returnBall(t, -5)
+	down := returnBall(t, 5)
+	if !near(still.Vy, 0) {
+		t.Errorf("still paddle sent the ball off with Vy %v, want straight back", still.Vy)
+	}
+	if up.Vy >= still.Vy || down.Vy <= still.Vy {
+		t.Errorf("Vy %v moving up, %v still, %v moving down; want the ball to follow the paddle", up.Vy, still.Vy, down.Vy)
+	}
+	if !near(up.Vy, -down.Vy) {
+		t.Errorf("spin up %v and down %v aren't mirror images", up.Vy, down.Vy)
+	}
+	speed := math.Hypot(still.Vx, still.Vy)
+	for _, b := range []Ball{up, down} {
+		if !near(math.Hypot(b.Vx, b.Vy), speed) {
+			t.Errorf("spin changed the speed from %v to %v", speed, math.Hypot(b.Vx, b.Vy))
+		}
+	}
+}
+
+func TestSpinCapped(t *testing.T) {
+	setFlag(t, spinAddsSpeed, false)
+	fast := returnBall(t, -100)
+	// Just enough motion to reach the cap
+	capped := returnBall(t, -int(math.Ceil(*maxSpin / *spinFactor)))
+	if !near(fast.Vy, capped.Vy) {
+		t.Fatalf("flicked paddle gave Vy %v, want the capped %v", fast.Vy, capped.Vy)
+	}
+}
+
+func TestSpinAddsSpeed(t *testing.T) {
+	setFlag(t, spinAddsSpeed, true)
+	still := returnBall(t, 0)
+	up := returnBall(t, -5)
+	if math.Hypot(up.Vx, up.Vy) <= math.Hypot(still.Vx, still.Vy) {
+		t.Fatalf("spin left the ball at %v, want faster than %v", math.Hypot(up.Vx, up.Vy), math.Hypot(still.Vx, still.Vy))
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+)
+
+// Static file server options
+var (
+	serveStatic = flag.Bool("static", true, "serve the web client; disable to run as a pure game backend")
+	staticDir   = flag.String("static-dir", "./public", "directory to serve the web client from")
+)
+
+// mountStatic serves the web client at the root of mux, unless it is disabled
+func mountStatic(mux *http.ServeMux) {
+	if !*serveStatic {
+		slog.Info("Static file server disabled")
+		return
+	}
+	mux.Handle("/", http.FileServer(http.Dir(*staticDir)))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticServingDisabled(t *testing.T) {
+	setFlag(t, serveStatic, false)
+	_, ts := newTestServer(t, testConfig(t))
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET / returned %d with static serving disabled, want 404", resp.StatusCode)
+	}
+
+	conn := dialTest(t, ts, "")
+	if msg := readUntil(t, conn, AssignMessage); msg.Player != "left" {
+		t.Fatalf("assigned %q, want left", msg.Player)
+	}
+}
+
+func TestStaticDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("pong"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, staticDir, dir)
+	_, ts := newTestServer(t, testConfig(t))
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Fatalf("GET / returned %d %q, want the index from -static-dir", resp.StatusCode, body)
+	}
+}
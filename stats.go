@@ -0,0 +1,308 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Where player statistics are kept between runs. Empty keeps them in memory only.
+var statsFile = flag.String("stats-file", "", "persist player statistics to this JSON file (empty keeps them in memory only)")
+
+// PlayerStats is a named player's record across matches
+type PlayerStats struct {
+	Name   string `json:"name"`
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+	Points int    `json:"points"` // Points scored
+}
+
+// StatsStore keeps player statistics. Implementations must be safe for
+// concurrent use.
+type StatsStore interface {
+	// Get returns a player's statistics, zero if they have none yet
+	Get(name string) (PlayerStats, error)
+	// AddMatch records one finished match for a player
+	AddMatch(name string, won bool, points int) error
+	// Top returns up to limit named players, best first; see rankBefore
+	Top(limit int) ([]PlayerStats, error)
+	// Close writes out any changes not yet saved
+	Close() error
+}
+
+// Players /leaderboard returns by default and at most
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+// Prefix of the names made up for anonymous players
+const guestPrefix = "guest-"
+
+// jsonStatsStore keeps statistics in memory, rewriting the whole file (if
+// any) from its own goroutine after changes, so a match ending never waits on
+// the disk. Changes made while a save is running go out together in the next.
+type jsonStatsStore struct {
+	path  string
+	mu    sync.Mutex
+	stats map[string]PlayerStats
+	// Named players in leaderboard order, rebuilt on the first Top after a change
+	ranked []PlayerStats
+	// Wakes the writer when there are changes to save, nil without a file or
+	// once closed; saved is closed when the writer has finished
+	changed chan struct{}
+	saved   chan struct{}
+}
+
+// openJSONStatsStore loads the statistics file, which may not exist yet
+func openJSONStatsStore(path string) (*jsonStatsStore, error) {
+	s := &jsonStatsStore{path: path, stats: make(map[string]PlayerStats)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &s.stats); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	s.changed, s.saved = make(chan struct{}, 1), make(chan struct{})
+	go s.saveChanges(s.changed)
+	return s, nil
+}
+
+func (s *jsonStatsStore) Get(name string) (PlayerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats[name]
+	stats.Name = name
+	return stats, nil
+}
+
+func (s *jsonStatsStore) AddMatch(name string, won bool, points int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats[name]
+	stats.Name = name
+	if won {
+		stats.Wins++
+	} else {
+		stats.Losses++
+	}
+	stats.Points += points
+	s.stats[name] = stats
+	s.ranked = nil
+	// The writer has a save coming if it can't take another signal
+	if s.changed != nil {
+		select {
+		case s.changed <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *jsonStatsStore) Top(limit int) ([]PlayerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ranked == nil {
+		s.ranked = make([]PlayerStats, 0, len(s.stats))
+		for name, stats := range s.stats {
+			// Guests change name every visit, so their records mean nothing
+			if strings.HasPrefix(name, guestPrefix) {
+				continue
+			}
+			stats.Name = name
+			s.ranked = append(s.ranked, stats)
+		}
+		slices.SortFunc(s.ranked, rankBefore)
+	}
+	return slices.Clone(s.ranked[:min(limit, len(s.ranked))]), nil
+}
+
+func (s *jsonStatsStore) Close() error {
+	s.mu.Lock()
+	changed := s.changed
+	s.changed = nil
+	s.mu.Unlock()
+	if changed == nil {
+		return nil
+	}
+	close(changed)
+	<-s.saved
+	return nil
+}
+
+// saveChanges writes the file whenever the statistics change, until the
+// store is closed
+func (s *jsonStatsStore) saveChanges(changed <-chan struct{}) {
+	defer close(s.saved)
+	for range changed {
+		if err := s.save(); err != nil {
+			slog.Error("Error saving player statistics", "file", s.path, "err", err)
+		}
+	}
+}
+
+// rankBefore orders players by wins, then by the share of their matches they
+// won, then by name
+func rankBefore(a, b PlayerStats) int {
+	if a.Wins != b.Wins {
+		return cmp.Compare(b.Wins, a.Wins)
+	}
+	// a wins a.Wins/(a.Wins+a.Losses), compared without dividing by zero
+	if c := cmp.Compare(b.Wins*(a.Wins+a.Losses), a.Wins*(b.Wins+b.Losses)); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
+
+// save writes the statistics through a temporary file so a crash never
+// leaves a half-written one
+func (s *jsonStatsStore) save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.stats, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".stats-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// resolvePlayerName validates a name from the query string, making up a
+// guest name for anonymous players
+func resolvePlayerName(name string) (string, error) {
+	if name == "" {
+		token := newToken()
+		if token == "" {
+			return "", errNoToken
+		}
+		return guestPrefix + token[:6], nil
+	}
+	return validateSideName(name)
+}
+
+// setPlayerName records who plays a side, or forgets them with "". Caller
+// must hold room lock.
+func (room *Room) setPlayerName(player, name string) {
+	if name == "" {
+		delete(room.PlayerNames, player)
+		return
+	}
+	if room.PlayerNames == nil {
+		room.PlayerNames = make(map[string]string)
+	}
+	room.PlayerNames[player] = name
+}
+
+// recordStats credits a finished match to the named players who played it.
+// Sides played by the AI have no name and aren't counted.
+func (s *Server) recordStats(roomID string, result MatchResult) {
+	for side, name := range result.Players {
+		// Quad matches are won by elimination, not points
+		points := 0
+		switch side {
+		case "left":
+			points = result.ScoreLeft
+		case "right":
+			points = result.ScoreRight
+		}
+		if err := s.stats.AddMatch(name, side == result.Winner, points); err != nil {
+			slog.Error("Error recording player statistics", "room", roomID, "player", side, "name", name, "err", err)
+		}
+	}
+}
+
+// closeStats writes out player statistics still waiting to be saved
+func (s *Server) closeStats() {
+	if s.stats == nil {
+		return
+	}
+	if err := s.stats.Close(); err != nil {
+		slog.Error("Error closing player statistics", "err", err)
+	}
+}
+
+// handleStats serves a player's statistics as JSON
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.stats.Get(name)
+	if err != nil {
+		slog.Error("Error reading player statistics", "name", name, "err", err)
+		http.Error(w, "statistics unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		slog.Error("Error encoding player statistics", "err", err)
+	}
+}
+
+// handleLeaderboard serves the top named players as JSON, most wins first.
+// ?limit= asks for up to maxLeaderboardSize of them.
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLeaderboardSize {
+			http.Error(w, fmt.Sprintf("limit must be 1-%d", maxLeaderboardSize), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	top, err := s.stats.Top(limit)
+	if err != nil {
+		slog.Error("Error reading leaderboard", "err", err)
+		http.Error(w, "leaderboard unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(top); err != nil {
+		slog.Error("Error encoding leaderboard", "err", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestJSONStatsStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	store, err := openJSONStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.AddMatch("alice", i%2 == 0, 3); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := openJSONStatsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	got, err := reopened.Get("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PlayerStats{Name: "alice", Wins: 10, Losses: 10, Points: 60}
+	if got != want {
+		t.Fatalf("stats after reopening = %+v, want %+v", got, want)
+	}
+}
+
+func TestGuestNameWithoutRandomness(t *testing.T) {
+	randRead = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+	t.Cleanup(func() { randRead = rand.Read })
+
+	if _, err := resolvePlayerName(""); !errors.Is(err, errNoToken) {
+		t.Fatalf("guest name without randomness gave %v, want errNoToken", err)
+	}
+	_, ts := newTestServer(t, testConfig(t))
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL(ts, ""), nil)
+	if err == nil {
+		t.Fatal("anonymous player joined without a guest name")
+	}
+	if resp == nil || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("anonymous join got %v, want a 500", resp)
+	}
+}
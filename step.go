@@ -0,0 +1,37 @@
+package main
+
+import "time"
+
+// Simulated time a stepped room starts from
+var stepEpoch = time.Unix(0, 0)
+
+// NewSteppedRoom returns a room that only moves when Step is called, for
+// reproducing games exactly: no ticker drives it, time advances by one tick
+// interval per step and every random draw comes from opts.Rand. Both paddles
+// count as taken, so it counts down and plays without connections. It is
+// not registered with the server.
+func (s *Server) NewSteppedRoom(id string, opts RoomOptions) *Room {
+	room := s.newRoom(id, opts)
+	room.stepped = true
+	room.simTime = stepEpoch
+	return room
+}
+
+// Step advances a stepped or frozen room by one tick and returns the result
+// if the match ended on it
+func (room *Room) Step() *MatchResult {
+	room.Lock()
+	room.simTime = room.simTime.Add(room.Config.TickInterval())
+	now := room.simTime
+	room.Unlock()
+	return room.tick(now)
+}
+
+// now is the room's clock: the wall clock, less any time spent frozen, or
+// the simulated time of a stepped or frozen room. Caller must hold room lock.
+func (room *Room) now() time.Time {
+	if room.stepped || room.frozen {
+		return room.simTime
+	}
+	return time.Now().Add(-room.clockLag)
+}
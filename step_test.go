@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// tickState is what a test compares between runs after each step
+type tickState struct {
+	Ball                  Ball
+	ScoreLeft, ScoreRight int
+	State                 string
+}
+
+// runSteps plays a seeded stepped room for n ticks and returns its state after each
+func runSteps(t *testing.T, seed int64, n int) []tickState {
+	t.Helper()
+	setFlag(t, serveDelay, 0)
+	room := NewServer(testConfig(t), nil).NewSteppedRoom("steps", RoomOptions{Mode: ClassicMode, Balls: 1, Rand: rand.New(rand.NewSource(seed))})
+	states := make([]tickState, 0, n)
+	for range n {
+		room.Step()
+		room.Lock()
+		states = append(states, tickState{room.Ball, room.ScoreLeft, room.ScoreRight, room.State})
+		room.Unlock()
+	}
+	return states
+}
+
+func TestSteppedRoomIsDeterministic(t *testing.T) {
+	const ticks = 3000
+	first := runSteps(t, 7, ticks)
+	second := runSteps(t, 7, ticks)
+	scored := false
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("tick %d: %+v then %+v from the same seed", i+1, first[i], second[i])
+		}
+		scored = scored || first[i].ScoreLeft+first[i].ScoreRight > 0
+	}
+	if !scored {
+		t.Fatalf("no point in %d ticks with nobody defending", ticks)
+	}
+
+	other := runSteps(t, 8, ticks)
+	same := true
+	for i := range first {
+		same = same && first[i] == other[i]
+	}
+	if same {
+		t.Fatal("a different seed played out the same")
+	}
+}
+
+func TestStepMovesBallOneTick(t *testing.T) {
+	states := runSteps(t, 1, 400)
+	// In open court the ball moves by exactly its velocity each step
+	moved := 0
+	for i := 1; i < len(states); i++ {
+		prev, cur := states[i-1], states[i]
+		if prev.State != StatePlaying || cur.Ball.Vx != prev.Ball.Vx || cur.Ball.Vy != prev.Ball.Vy {
+			continue
+		}
+		if cur.Ball.X != prev.Ball.X+prev.Ball.Vx || cur.Ball.Y != prev.Ball.Y+prev.Ball.Vy {
+			t.Fatalf("tick %d: ball went from (%v, %v) at (%v, %v) to (%v, %v)",
+				i+1, prev.Ball.X, prev.Ball.Y, prev.Ball.Vx, prev.Ball.Vy, cur.Ball.X, cur.Ball.Y)
+		}
+		moved++
+	}
+	if moved == 0 {
+		t.Fatal("ball never moved freely")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestScoreSubscriberGetsNoPositions(t *testing.T) {
+	setFlag(t, winningScore, 2)
+	setFlag(t, serveDelay, 0)
+	cfg := testConfig(t)
+	cfg.TickMs = 4
+	_, ts := newTestServer(t, cfg)
+	conn := dialTest(t, ts, "?ai=hard")
+	readUntil(t, conn, AssignMessage)
+	if err := conn.WriteJSON(Message{Type: SubscribeMsg, Subscription: ScoreSubscription}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The countdown leaves the subscription time to take effect before the
+	// first point; from then on only scores and match events arrive
+	readUntil(t, conn, PointMsg)
+	points := 1
+	for {
+		msg, err := readMessage(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch msg.Type {
+		case UpdateMessage, DeltaMsg:
+			t.Fatalf("score subscriber got a %s", msg.Type)
+		case PointMsg:
+			points++
+		case GameOverMsg:
+			if points != msg.ScoreLeft+msg.ScoreRight {
+				t.Fatalf("game over at %d-%d after %d point messages", msg.ScoreLeft, msg.ScoreRight, points)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// How often the ball speeds up in survival mode, by -speedup each time
+var survivalRamp = flag.Duration("survival-ramp", 5*time.Second, "how often the ball speeds up during a survival run, by the -speedup factor")
+
+// AI level that plays the other paddle in survival mode unless the player
+// asks for another with ?ai=
+const survivalAI = "hard"
+
+// Players kept on the survival leaderboard, each with their best run only
+const maxSurvivalScores = 10
+
+// SurvivalScore is a player's longest survival run
+type SurvivalScore struct {
+	Name    string    `json:"name"`
+	Rally   int       `json:"rally"` // Returns before the miss
+	EndedAt time.Time `json:"endedAt"`
+}
+
+// survivor returns the side the lone survival or practice player holds. The
+// AI or the practice wall has the other. Caller must hold room lock.
+func (room *Room) survivor() string {
+	if room.Mode == PracticeMode {
+		return opponent(*practiceWall)
+	}
+	return opponent(room.emptySide())
+}
+
+// countReturn credits a paddle hit to the run in progress when the lone
+// player made it. Caller must hold room lock.
+func (room *Room) countReturn(side string) {
+	if room.countsReturns() && side == room.survivor() {
+		room.Rally++
+	}
+}
+
+// survivalRally returns the run in progress for broadcasting, or nil outside
+// survival and practice modes. Caller must hold room lock.
+func (room *Room) survivalRally() *int {
+	if !room.countsReturns() {
+		return nil
+	}
+	rally := room.Rally
+	return &rally
+}
+
+// rampSurvival speeds every ball up once per survivalRamp of play, within
+// speedCap. Caller must hold room lock.
+func (room *Room) rampSurvival(now time.Time) {
+	if room.Mode != SurvivalMode || *survivalRamp <= 0 {
+		return
+	}
+	if room.rampAt.IsZero() {
+		room.rampAt = now.Add(*survivalRamp)
+		return
+	}
+	if now.Before(room.rampAt) {
+		return
+	}
+	room.rampAt = room.rampAt.Add(*survivalRamp)
+	for _, ball := range room.balls() {
+		speed := math.Hypot(ball.Vx, ball.Vy)
+		if speed == 0 {
+			continue
+		}
+		scale := math.Min(speed**speedup, math.Max(speed, room.speedCap())) / speed
+		ball.Vx *= scale
+		ball.Vy *= scale
+	}
+}
+
+// survivalMiss handles a ball leaving the court in survival mode. The AI
+// missing doesn't cost the player anything: the ball is served to them again
+// and the run goes on. The player missing ends the run, whose length is
+// returned as the result. Caller must hold room lock.
+func (room *Room) survivalMiss(conceded string) *MatchResult {
+	player := room.survivor()
+	room.resetGame(player)
+	if conceded != player {
+		return nil
+	}
+
+	rally := room.Rally
+	room.BestRally = max(room.BestRally, rally)
+	room.Rally = 0
+	room.rampAt = time.Time{}
+	slog.Info("Survival run ended", "room", room.ID, "player", player, "rally", rally, "best", room.BestRally)
+
+	result := room.endMatch(opponent(player))
+	result.Mode = SurvivalMode
+	result.Rally = rally
+	result.BestRally = room.BestRally
+	return result
+}
+
+// recordSurvival puts a finished run on the leaderboard if it is the
+// player's best. The AI's side has no name and isn't counted.
+func (s *Server) recordSurvival(result MatchResult) {
+	for _, name := range result.Players {
+		s.survivalMutex.Lock()
+		i := slices.IndexFunc(s.survivalScores, func(score SurvivalScore) bool { return score.Name == name })
+		switch {
+		case i < 0:
+			s.survivalScores = append(s.survivalScores, SurvivalScore{Name: name, Rally: result.Rally, EndedAt: time.Now()})
+		case s.survivalScores[i].Rally < result.Rally:
+			s.survivalScores[i] = SurvivalScore{Name: name, Rally: result.Rally, EndedAt: time.Now()}
+		}
+		slices.SortStableFunc(s.survivalScores, func(a, b SurvivalScore) int { return b.Rally - a.Rally })
+		if len(s.survivalScores) > maxSurvivalScores {
+			s.survivalScores = s.survivalScores[:maxSurvivalScores]
+		}
+		s.survivalMutex.Unlock()
+	}
+}
+
+// handleSurvival serves the survival leaderboard as JSON, longest run first
+func (s *Server) handleSurvival(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.survivalMutex.Lock()
+	scores := slices.Clone(s.survivalScores)
+	s.survivalMutex.Unlock()
+	if scores == nil {
+		scores = []SurvivalScore{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(scores); err != nil {
+		slog.Error("Error encoding survival leaderboard", "err", err)
+	}
+}
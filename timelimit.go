@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"math"
+)
+
+// Play time before a match is decided on the score. A match level when time
+// runs out goes to sudden death: the next point wins.
+var timeLimit = flag.Duration("time-limit", 0, "decide matches on the score after this much play, sudden death on a tie (0 plays to the winning score)")
+
+// timed reports whether the room's matches run against the clock. Quad
+// matches are won by elimination and never are. Caller must hold room lock.
+func (room *Room) timed() bool {
+	return *timeLimit > 0 && room.Mode == ClassicMode
+}
+
+// runClock counts one tick of play against the time limit and decides the
+// match when it runs out. It is only called for ticks the ball is in play,
+// so the clock stands still through countdowns and pauses. Caller must hold
+// room lock.
+func (room *Room) runClock() *MatchResult {
+	if !room.timed() || room.SuddenDeath {
+		return nil
+	}
+	room.clockUsed += room.Config.TickInterval()
+	if room.clockUsed < *timeLimit {
+		return nil
+	}
+	if room.ScoreLeft == room.ScoreRight {
+		room.SuddenDeath = true
+		slog.Info("Time is up with scores level, playing sudden death", "room", room.ID, "score", room.ScoreLeft)
+		return nil
+	}
+	winner := "left"
+	if room.ScoreRight > room.ScoreLeft {
+		winner = "right"
+	}
+	slog.Info("Time is up", "room", room.ID, "player", winner, "score_left", room.ScoreLeft, "score_right", room.ScoreRight)
+	// The rally in progress is abandoned; the loser receives the next serve
+	room.resetGame(opponent(winner))
+	room.holdServe(opponent(winner))
+	return room.endMatch(winner)
+}
+
+// resetClock puts the full time limit back for the next match. Caller must
+// hold room lock.
+func (room *Room) resetClock() {
+	room.clockUsed = 0
+	room.SuddenDeath = false
+}
+
+// timeLeft returns the whole seconds left on the match clock for
+// broadcasting, or nil when matches aren't timed. Caller must hold room lock.
+func (room *Room) timeLeft() *int {
+	if !room.timed() {
+		return nil
+	}
+	left := int(math.Ceil(max(*timeLimit-room.clockUsed, 0).Seconds()))
+	return &left
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"os"
+)
+
+// TLS options. With a certificate and key the server speaks HTTPS and wss://
+// only; without them it stays plaintext for local development. Container
+// deployments can set PONG_TLS_CERT and PONG_TLS_KEY instead; flags win.
+var (
+	tlsCert = flag.String("tls-cert", "", "PEM certificate file for serving HTTPS and wss:// (default $PONG_TLS_CERT)")
+	tlsKey  = flag.String("tls-key", "", "PEM private key file for -tls-cert (default $PONG_TLS_KEY)")
+)
+
+// tlsFiles returns the certificate and key to serve with, or two empty
+// strings for plaintext
+func tlsFiles() (cert, key string, err error) {
+	cert = cmp.Or(*tlsCert, os.Getenv("PONG_TLS_CERT"))
+	key = cmp.Or(*tlsKey, os.Getenv("PONG_TLS_KEY"))
+	if (cert == "") != (key == "") {
+		return "", "", errors.New("a certificate and a key must be given together")
+	}
+	return cert, key, nil
+}
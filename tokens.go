@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+
+	"github.com/gorilla/websocket"
+)
+
+// randRead fills newToken's bytes; tests swap it to make it fail
+var randRead = rand.Read
+
+// errNoToken is returned where a random token was needed but none could be made
+var errNoToken = errors.New("could not generate a random token")
+
+// newToken returns a random reconnection token, or "" if the system's random
+// source failed
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := randRead(b); err != nil {
+		slog.Error("Error generating reconnection token", "err", err)
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// reclaimSide returns the paddle a reconnection token was issued for, or "".
+// If a stale connection still holds that paddle it loses it and is closed, so
+// a refreshed page doesn't have to wait for the old one to time out. Caller
+// must hold assignMutex.
+func (room *Room) reclaimSide(token string) string {
+	if token == "" {
+		return ""
+	}
+	side := ""
+	for s, t := range room.tokens {
+		if t == token {
+			side = s
+		}
+	}
+	if side == "" {
+		return ""
+	}
+	for conn, role := range room.assignedPlayers {
+		if role == side {
+			slog.Info("Replacing stale connection", "room", room.ID, "remote_addr", conn.RemoteAddr().String(), "player", side)
+			room.unassign(conn)
+			room.closeConn(conn, CloseReplaced, "replaced by a newer connection")
+		}
+	}
+	return side
+}
+
+// paddleToken returns the reconnection token issued for a paddle
+func (room *Room) paddleToken(side string) string {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+	return room.tokens[side]
+}
+
+// paddleOf returns the paddle a connection holds, or "" if none
+func (room *Room) paddleOf(conn *websocket.Conn) string {
+	room.assignMutex.Lock()
+	defer room.assignMutex.Unlock()
+	return room.assignedPlayers[conn]
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Invalid messages a connection may send before it is disconnected
+const maxInvalidMessages = 10
+
+// Messages clients may send
+var clientMessages = map[string]bool{
+	MoveMessage:     true,
+	AimMessage:      true,
+	ServeMessage:    true,
+	VisibilityMsg:   true,
+	SettingsMessage: true,
+	VoteMessage:     true,
+	SubscribeMsg:    true,
+	ChatMessage:     true,
+	EmoteMessage:    true,
+	ColorMessage:    true,
+}
+
+// isDecodeError reports whether a read failed only because the frame wasn't
+// a JSON message, leaving the connection usable. Lost connections surface as
+// close or network errors instead; an empty or truncated frame decodes to
+// io.ErrUnexpectedEOF.
+func isDecodeError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// MessageError explains why a client message was rejected. Code is
+// UnknownTypeError, MissingFieldError or OutOfRangeError.
+type MessageError struct {
+	Code   string
+	Reason string
+}
+
+func (e *MessageError) Error() string {
+	return e.Reason
+}
+
+// validateMessage checks that a client message has a known type and the
+// fields that type needs, in range
+func validateMessage(msg Message, cfg Config) error {
+	if !clientMessages[msg.Type] {
+		return &MessageError{UnknownTypeError, fmt.Sprintf("unknown message type %q", msg.Type)}
+	}
+	switch msg.Type {
+	case MoveMessage:
+		if msg.Direction != nil {
+			if *msg.Direction < -1 || *msg.Direction > 1 {
+				return &MessageError{OutOfRangeError, fmt.Sprintf("direction %d must be -1, 0 or 1", *msg.Direction)}
+			}
+			break
+		}
+		if msg.X != nil {
+			if maxX := cfg.MaxPaddleX(); *msg.X < 0 || *msg.X > maxX {
+				return &MessageError{OutOfRangeError, fmt.Sprintf("x %d is outside 0-%d", *msg.X, maxX)}
+			}
+			break
+		}
+		if msg.Y == nil {
+			return &MessageError{MissingFieldError, "move needs x, y or direction"}
+		}
+		if maxY := cfg.MaxPaddleY(); *msg.Y < 0 || *msg.Y > maxY {
+			return &MessageError{OutOfRangeError, fmt.Sprintf("y %d is outside 0-%d", *msg.Y, maxY)}
+		}
+	case AimMessage:
+		if msg.Angle == nil {
+			return &MessageError{MissingFieldError, "aim needs angle"}
+		}
+	case VisibilityMsg:
+		if msg.Visibility != Foreground && msg.Visibility != Background {
+			return &MessageError{OutOfRangeError, fmt.Sprintf("visibility %q must be %q or %q", msg.Visibility, Foreground, Background)}
+		}
+	case SettingsMessage:
+		if msg.Physics == "" {
+			return &MessageError{MissingFieldError, "settings needs physics"}
+		}
+	case VoteMessage:
+		if msg.Option == "" {
+			return &MessageError{MissingFieldError, "vote needs option"}
+		}
+	case ChatMessage:
+		if msg.Text == "" {
+			return &MessageError{MissingFieldError, "chat needs text"}
+		}
+		if _, err := cleanChatText(msg.Text); err != nil {
+			return &MessageError{OutOfRangeError, err.Error()}
+		}
+	case EmoteMessage:
+		if msg.Emote == "" {
+			return &MessageError{MissingFieldError, "emote needs emote"}
+		}
+		if !emotes[msg.Emote] {
+			return &MessageError{OutOfRangeError, fmt.Sprintf("unknown emote %q", msg.Emote)}
+		}
+	case ColorMessage:
+		if msg.Color == "" {
+			return &MessageError{MissingFieldError, "color needs color"}
+		}
+		if _, err := validateColor(msg.Color); err != nil {
+			return &MessageError{OutOfRangeError, err.Error()}
+		}
+	case SubscribeMsg:
+		if msg.Subscription != FullSubscription && msg.Subscription != ScoreSubscription {
+			return &MessageError{OutOfRangeError, fmt.Sprintf("subscription %q must be %q or %q", msg.Subscription, FullSubscription, ScoreSubscription)}
+		}
+	}
+	return nil
+}
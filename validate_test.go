@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestValidateMessage(t *testing.T) {
+	cfg := Config{Width: 800, Height: 600, PaddleWidth: 10, PaddleHeight: 100}
+	n := func(v int) *int { return &v }
+	angle := 0.3
+	tests := []struct {
+		name string
+		msg  Message
+		code string // Empty if valid
+	}{
+		{"move", Message{Type: MoveMessage, Y: n(250)}, ""},
+		{"move to the top", Message{Type: MoveMessage, Y: n(0)}, ""},
+		{"move to the bottom", Message{Type: MoveMessage, Y: n(500)}, ""},
+		{"move above the court", Message{Type: MoveMessage, Y: n(-1)}, OutOfRangeError},
+		{"move below the court", Message{Type: MoveMessage, Y: n(501)}, OutOfRangeError},
+		{"move without a position", Message{Type: MoveMessage}, MissingFieldError},
+		{"move along x", Message{Type: MoveMessage, X: n(700)}, ""},
+		{"move off the end", Message{Type: MoveMessage, X: n(701)}, OutOfRangeError},
+		{"hold up", Message{Type: MoveMessage, Direction: n(-1)}, ""},
+		{"hold too hard", Message{Type: MoveMessage, Direction: n(2)}, OutOfRangeError},
+		{"unknown type", Message{Type: "teleport"}, UnknownTypeError},
+		{"no type", Message{}, UnknownTypeError},
+		{"server-only type", Message{Type: UpdateMessage}, UnknownTypeError},
+		{"aim", Message{Type: AimMessage, Angle: &angle}, ""},
+		{"aim nowhere", Message{Type: AimMessage}, MissingFieldError},
+		{"serve", Message{Type: ServeMessage}, ""},
+		{"background", Message{Type: VisibilityMsg, Visibility: Background}, ""},
+		{"half visible", Message{Type: VisibilityMsg, Visibility: "half"}, OutOfRangeError},
+		{"settings", Message{Type: SettingsMessage, Physics: "curve"}, ""},
+		{"empty settings", Message{Type: SettingsMessage}, MissingFieldError},
+		{"vote", Message{Type: VoteMessage, Option: "fast"}, ""},
+		{"blank vote", Message{Type: VoteMessage}, MissingFieldError},
+		{"chat", Message{Type: ChatMessage, Text: "gg"}, ""},
+		{"empty chat", Message{Type: ChatMessage}, MissingFieldError},
+		{"emote", Message{Type: EmoteMessage, Emote: "gg"}, ""},
+		{"unknown emote", Message{Type: EmoteMessage, Emote: "shrug"}, OutOfRangeError},
+		{"no emote", Message{Type: EmoteMessage}, MissingFieldError},
+		{"no color", Message{Type: ColorMessage}, MissingFieldError},
+		{"subscribe to scores", Message{Type: SubscribeMsg, Subscription: ScoreSubscription}, ""},
+		{"subscribe to gossip", Message{Type: SubscribeMsg, Subscription: "gossip"}, OutOfRangeError},
+	}
+	for _, tt := range tests {
+		err := validateMessage(tt.msg, cfg)
+		if tt.code == "" {
+			if err != nil {
+				t.Errorf("%s: rejected with %v", tt.name, err)
+			}
+			continue
+		}
+		var msgErr *MessageError
+		if !errors.As(err, &msgErr) || msgErr.Code != tt.code || msgErr.Reason == "" {
+			t.Errorf("%s: got %v, want a %s error with a reason", tt.name, err, tt.code)
+		}
+	}
+}
+
+func TestInvalidMessagesAnsweredThenDisconnected(t *testing.T) {
+	_, ts := newTestServer(t, testConfig(t))
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	y := -5
+	if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &y}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readUntil(t, conn, ErrorMessage); msg.Code != OutOfRangeError || !strings.Contains(msg.Hint, "-5") {
+		t.Fatalf("got %q %q for a move off the court, want %s naming the position", msg.Code, msg.Hint, OutOfRangeError)
+	}
+
+	for range maxInvalidMessages {
+		if err := conn.WriteJSON(Message{Type: "teleport"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for {
+		_, err := readMessage(conn)
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != websocket.ClosePolicyViolation {
+				t.Fatalf("closed with %d, want %d", closeErr.Code, websocket.ClosePolicyViolation)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("connection ended with %v, want a close frame", err)
+		}
+	}
+}
+
+func TestGarbageFrameThenMoveApplied(t *testing.T) {
+	setFlag(t, maxPaddleJump, 0)
+	s, ts := newTestServer(t, testConfig(t))
+	conn := dialTest(t, ts, "")
+	readUntil(t, conn, AssignMessage)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"move","y":`)); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readUntil(t, conn, ErrorMessage); msg.Code != MalformedError {
+		t.Fatalf("got %q for a garbage frame, want %s", msg.Code, MalformedError)
+	}
+
+	y := 200
+	if err := conn.WriteJSON(Message{Type: MoveMessage, Y: &y}); err != nil {
+		t.Fatal(err)
+	}
+	room := s.lookupRoom(DefaultRoomID)
+	waitFor(t, "the move after the garbage frame", func() bool {
+		room.Lock()
+		defer room.Unlock()
+		return room.PanYLeft == y
+	})
+	// Still connected and being sent updates
+	readUntil(t, conn, UpdateMessage)
+}
@@ -0,0 +1,62 @@
+package main
+
+import "math"
+
+// setDirection records the direction a player's paddle should move each
+// tick: -1 up (left for flat paddles), 1 down (right), 0 stop. Caller must
+// hold room lock.
+func (room *Room) setDirection(player string, direction int) {
+	if room.Directions[player] != direction {
+		delete(room.paddleCarry, player)
+	}
+	if direction == 0 {
+		delete(room.Directions, player)
+		return
+	}
+	if room.Directions == nil {
+		room.Directions = make(map[string]int)
+	}
+	room.Directions[player] = direction
+}
+
+// movePaddles advances paddles moving in velocity mode by one tick. Caller
+// must hold room lock.
+func (room *Room) movePaddles() {
+	for player, direction := range room.Directions {
+		if room.hasEffect(MirrorEffect, player) {
+			direction = -direction
+		}
+		step := room.paddleStep(player, direction)
+		if x := room.paddleX(player); x != nil {
+			if moved := room.Config.clampXPosition(*x + step); moved != *x {
+				*x = moved
+				room.markActivity()
+			}
+			continue
+		}
+		y := &room.PanYLeft
+		if player == "right" {
+			y = &room.PanYRight
+		}
+		moved := room.clampToReach(player, room.Config.clampYPosition(*y+step))
+		if moved != *y {
+			*y = moved
+			room.markActivity()
+		}
+	}
+}
+
+// paddleStep returns the whole pixels a paddle moves this tick at
+// Config.PaddleSpeed, carrying the fraction over so that over a second it
+// moves exactly that far. Caller must hold room lock.
+func (room *Room) paddleStep(player string, direction int) int {
+	exact := float64(direction)*room.Config.PaddleSpeed*room.Config.TickInterval().Seconds() + room.paddleCarry[player]
+	// Rounded to a millionth of a pixel so float error can't lose a whole one
+	exact = math.Round(exact*1e6) / 1e6
+	step := math.Trunc(exact)
+	if room.paddleCarry == nil {
+		room.paddleCarry = make(map[string]float64)
+	}
+	room.paddleCarry[player] = exact - step
+	return int(step)
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestPaddleSpeedOverOneSecond(t *testing.T) {
+	tests := []struct {
+		name      string
+		tickMs    int
+		speed     float64
+		start     int
+		direction int
+		want      int
+	}{
+		{"whole pixels a tick", 10, 300, 0, 1, 300},
+		{"fractions carried over", 10, 333, 0, 1, 333},
+		{"slower ticks", 20, 333, 0, 1, 333},
+		{"up", 10, 333, 400, -1, 67},
+		{"stopped by the bottom wall", 10, 333, 300, 1, 500},
+		{"stopped by the top wall", 10, 333, 100, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := newSteppedTestRoom(t)
+			room.Lock()
+			defer room.Unlock()
+			room.Config.TickMs = tt.tickMs
+			room.Config.PaddleSpeed = tt.speed
+			room.PanYLeft = tt.start
+			room.setDirection("left", tt.direction)
+			// One simulated second of ticks
+			for range 1000 / tt.tickMs {
+				room.movePaddles()
+			}
+			if room.PanYLeft != tt.want {
+				t.Fatalf("paddle from %d ended at %d, want %d", tt.start, room.PanYLeft, tt.want)
+			}
+		})
+	}
+}
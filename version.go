@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Minimum client version accepted by the server. Empty disables the check.
+var minClientVersion = flag.String("min-client-version", "", "reject clients reporting a version below this (e.g. 1.2.0)")
+
+// parseVersion parses a "major.minor.patch" version string. Missing parts
+// count as zero and a leading "v" or trailing pre-release tag is ignored.
+func parseVersion(v string) ([3]int, error) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return parts, fmt.Errorf("empty version")
+	}
+
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return parts, fmt.Errorf("invalid version %q", v)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// compareVersions returns -1, 0 or 1 if a is lower than, equal to or higher than b
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkClientVersion reports whether a client version satisfies the configured minimum
+func checkClientVersion(version string) error {
+	if *minClientVersion == "" {
+		return nil
+	}
+	min, err := parseVersion(*minClientVersion)
+	if err != nil {
+		return fmt.Errorf("bad -min-client-version: %w", err)
+	}
+	got, err := parseVersion(version)
+	if err != nil {
+		return fmt.Errorf("client version: %w", err)
+	}
+	if compareVersions(got, min) < 0 {
+		return fmt.Errorf("client version %s is below minimum %s", version, *minClientVersion)
+	}
+	return nil
+}
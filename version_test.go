@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    [3]int
+		wantErr bool
+	}{
+		{in: "1.2.3", want: [3]int{1, 2, 3}},
+		{in: "v2.0", want: [3]int{2, 0, 0}},
+		{in: "3", want: [3]int{3, 0, 0}},
+		{in: "1.4.0-beta.2", want: [3]int{1, 4, 0}},
+		{in: "", wantErr: true},
+		{in: "1.2.3.4", wantErr: true},
+		{in: "1.x", wantErr: true},
+		{in: "1.-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseVersion(tt.in)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("parseVersion(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOutdatedClientRejected(t *testing.T) {
+	setFlag(t, minClientVersion, "1.2.0")
+	_, ts := newTestServer(t, testConfig(t))
+
+	old := dialTest(t, ts, "?version=1.1.9")
+	msg := readUntil(t, old, ErrorMessage)
+	if msg.Code != ClientOutdatedError {
+		t.Fatalf("old client got error %q, want %q", msg.Code, ClientOutdatedError)
+	}
+	_, err := readMessage(old)
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseClientOutdated {
+		t.Fatalf("old client connection ended with %v, want close code %d", err, CloseClientOutdated)
+	}
+
+	current := dialTest(t, ts, "?version=1.2.0")
+	if msg := readUntil(t, current, AssignMessage); msg.Player != "left" {
+		t.Fatalf("current client assigned %q, want left", msg.Player)
+	}
+}
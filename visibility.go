@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+)
+
+// Visibility states reported by clients
+const (
+	Foreground = "foreground"
+	Background = "background"
+)
+
+// What to do with a player whose tab is in the background: "none", "pause" or "ai"
+var backgroundMode = flag.String("background-mode", "none", `handling for backgrounded players: "none", "pause" the game or hand the paddle to "ai"`)
+
+// setVisibility records a player's tab visibility. Caller must hold room lock.
+func (room *Room) setVisibility(player, visibility string) {
+	if room.Background == nil {
+		room.Background = make(map[string]bool)
+	}
+	background := visibility == Background
+	if room.Background[player] != background {
+		slog.Info("Player visibility changed", "room", room.ID, "player", player, "visibility", visibility)
+	}
+	if background {
+		room.Background[player] = true
+	} else {
+		delete(room.Background, player)
+	}
+}
+
+// pausedForBackground reports whether play is halted for a backgrounded player. Caller must hold room lock.
+func (room *Room) pausedForBackground() bool {
+	return *backgroundMode == "pause" && len(room.Background) > 0
+}
+
+// driveBackgroundPaddles lets the AI track the ball for backgrounded players. Caller must hold room lock.
+func (room *Room) driveBackgroundPaddles() {
+	if *backgroundMode != "ai" {
+		return
+	}
+	for player := range room.Background {
+		if horizontal(player) {
+			continue // The AI only drives upright paddles
+		}
+		room.driveAIPaddle(player)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+// stepUntilPlaying steps a stepped room through its countdown
+func stepUntilPlaying(t *testing.T, room *Room) {
+	t.Helper()
+	for range 10000 {
+		room.Lock()
+		playing := room.State == StatePlaying && room.serveAt.IsZero()
+		room.Unlock()
+		if playing {
+			return
+		}
+		room.Step()
+	}
+	t.Fatal("room never started playing")
+}
+
+func TestBackgroundPlayerHandedToAI(t *testing.T) {
+	setFlag(t, backgroundMode, "ai")
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	room.Ball = Ball{X: float64(room.Config.Width) / 2, Y: 20}
+	room.PanYLeft = room.Config.MaxPaddleY()
+	room.setVisibility("left", Background)
+	room.Unlock()
+
+	paddle := func() int {
+		room.Lock()
+		defer room.Unlock()
+		return room.PanYLeft
+	}
+	start := paddle()
+	for range 5 {
+		room.Step()
+	}
+	moved := paddle()
+	if moved >= start {
+		t.Fatalf("backgrounded paddle went from %d to %d, want the AI to move it up toward the ball", start, moved)
+	}
+
+	room.Lock()
+	room.setVisibility("left", Foreground)
+	room.Unlock()
+	for range 5 {
+		room.Step()
+	}
+	if y := paddle(); y != moved {
+		t.Fatalf("paddle moved from %d to %d after the player came back", moved, y)
+	}
+}
+
+func TestBackgroundPlayerPausesGame(t *testing.T) {
+	setFlag(t, backgroundMode, "pause")
+	setFlag(t, serveDelay, 0)
+	room := newSteppedTestRoom(t)
+	stepUntilPlaying(t, room)
+
+	ballX := func() float64 {
+		room.Lock()
+		defer room.Unlock()
+		return room.Ball.X
+	}
+	room.Lock()
+	room.setVisibility("right", Background)
+	room.Unlock()
+	x := ballX()
+	room.Step()
+	if got := ballX(); got != x {
+		t.Fatalf("ball moved from %v to %v with a player in the background", x, got)
+	}
+
+	room.Lock()
+	room.setVisibility("right", Foreground)
+	room.Unlock()
+	room.Step()
+	if got := ballX(); got == x {
+		t.Fatal("ball still held after the player came back")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/gorilla/websocket"
+)
+
+// Ball speeds (pixels per tick on each axis) that can be voted for the next match
+var voteOptions = map[string]float64{
+	"slow":   3,
+	"normal": 4,
+	"fast":   6,
+}
+
+// castVote records (or changes) a connection's vote and returns the new tally.
+// Votes are only taken in the lobby, before the match they're for starts.
+func (room *Room) castVote(conn *websocket.Conn, option string) (map[string]int, error) {
+	if _, ok := voteOptions[option]; !ok {
+		return nil, fmt.Errorf("unknown vote option %q", option)
+	}
+
+	room.Lock()
+	defer room.Unlock()
+	if !room.matchStarted.IsZero() {
+		return nil, fmt.Errorf("match in progress")
+	}
+
+	room.votesMutex.Lock()
+	defer room.votesMutex.Unlock()
+
+	room.votes[conn] = option
+	return room.tallyVotes(), nil
+}
+
+// dropVote forgets a departed connection's vote
+func (room *Room) dropVote(conn *websocket.Conn) {
+	room.votesMutex.Lock()
+	delete(room.votes, conn)
+	room.votesMutex.Unlock()
+}
+
+// tallyVotes counts votes per option. Caller must hold votesMutex.
+func (room *Room) tallyVotes() map[string]int {
+	tally := make(map[string]int, len(voteOptions))
+	for option := range voteOptions {
+		tally[option] = 0
+	}
+	for _, option := range room.votes {
+		tally[option]++
+	}
+	return tally
+}
+
+// applyVotes sets the ball speed for the starting match to the option with
+// the most votes, including that of a ball already served, and clears the
+// ballot. A tie keeps the current speed. Caller must hold room lock.
+func (room *Room) applyVotes() {
+	room.votesMutex.Lock()
+	defer room.votesMutex.Unlock()
+
+	if len(room.votes) == 0 {
+		return
+	}
+	winner, best, tied := "", 0, false
+	for option, n := range room.tallyVotes() {
+		switch {
+		case n > best:
+			winner, best, tied = option, n, false
+		case n == best:
+			tied = true
+		}
+	}
+	room.votes = make(map[*websocket.Conn]string)
+
+	if tied {
+		slog.Info("Vote tied, keeping current ball speed", "room", room.ID)
+		return
+	}
+	room.BallSpeed = voteOptions[winner]
+	speed := math.Min(math.Hypot(room.BallSpeed, room.BallSpeed), room.speedCap())
+	for _, ball := range room.balls() {
+		if current := math.Hypot(ball.Vx, ball.Vy); current > 0 {
+			ball.Vx *= speed / current
+			ball.Vy *= speed / current
+		}
+	}
+	slog.Info("Vote applied", "room", room.ID, "option", winner, "votes", best)
+}
+
+// broadcastVotes sends the running tally to every client
+func (room *Room) broadcastVotes(tally map[string]int) {
+	msg := Message{Type: VotesMessage, Tally: tally}
+	room.server.stampEvent(&msg)
+	room.broadcast(msg)
+}
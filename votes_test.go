@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestVotesAppliedAtMatchStart(t *testing.T) {
+	setFlag(t, serveDelay, 0)
+	s := NewServer(testConfig(t), nil)
+	room := s.NewSteppedRoom("votes", RoomOptions{Mode: ClassicMode, Balls: 1, Rand: rand.New(rand.NewSource(1))})
+	voters := []*websocket.Conn{new(websocket.Conn), new(websocket.Conn), new(websocket.Conn)}
+	for i, option := range []string{"fast", "slow", "fast"} {
+		if _, err := room.castVote(voters[i], option); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changing a vote replaces it
+	tally, err := room.castVote(voters[1], "fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tally["fast"] != 3 || tally["slow"] != 0 {
+		t.Fatalf("tally %v, want 3 fast and none slow", tally)
+	}
+	if _, err := room.castVote(voters[0], "warp"); err == nil {
+		t.Fatal("vote for an unknown option accepted")
+	}
+
+	room.Lock()
+	before := room.BallSpeed
+	room.Unlock()
+	for range 10000 {
+		room.Lock()
+		state, speed := room.State, room.BallSpeed
+		room.Unlock()
+		if state == StatePlaying {
+			break
+		}
+		if speed != before {
+			t.Fatalf("ball speed changed to %v before the match started", speed)
+		}
+		room.Step()
+	}
+
+	room.Lock()
+	defer room.Unlock()
+	if room.State != StatePlaying {
+		t.Fatalf("room still %s", room.State)
+	}
+	if room.BallSpeed != voteOptions["fast"] {
+		t.Fatalf("ball speed %v at match start, want the winning %v", room.BallSpeed, voteOptions["fast"])
+	}
+	want := math.Min(math.Hypot(room.BallSpeed, room.BallSpeed), room.speedCap())
+	if got := math.Hypot(room.Ball.Vx, room.Ball.Vy); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("served ball moving at %v, want %v", got, want)
+	}
+	if len(room.votes) != 0 {
+		t.Fatalf("%d votes left after the match started", len(room.votes))
+	}
+}
+
+func TestVotesRejectedDuringMatch(t *testing.T) {
+	s := NewServer(testConfig(t), nil)
+	room := s.NewSteppedRoom("votes", RoomOptions{Mode: ClassicMode, Balls: 1, Rand: rand.New(rand.NewSource(1))})
+	room.Lock()
+	room.State = StatePlaying
+	room.matchStarted = room.now()
+	room.Unlock()
+
+	if _, err := room.castVote(new(websocket.Conn), "slow"); err == nil {
+		t.Fatal("vote accepted during a match")
+	}
+	room.Lock()
+	defer room.Unlock()
+	if len(room.votes) != 0 {
+		t.Fatalf("%d votes recorded during a match", len(room.votes))
+	}
+}
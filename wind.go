@@ -0,0 +1,18 @@
+package main
+
+import "flag"
+
+// Wind options, in pixels per tick added to the ball's velocity every tick
+var (
+	windX = flag.Float64("wind-x", 0, "horizontal wind pushing the ball every tick (pixels/tick²)")
+	windY = flag.Float64("wind-y", 0, "vertical wind pushing the ball every tick (pixels/tick²)")
+)
+
+// windVector returns the wind for broadcasting, or nil when calm. Caller must hold room lock.
+func (room *Room) windVector() *Vector {
+	if room.Wind == (Vector{}) {
+		return nil
+	}
+	wind := room.Wind
+	return &wind
+}
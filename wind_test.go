@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestWindPushesBall(t *testing.T) {
+	setFlag(t, windX, 0.1)
+	setFlag(t, windY, -0.05)
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	if wind := room.windVector(); wind == nil || *wind != (Vector{X: 0.1, Y: -0.05}) {
+		t.Fatalf("broadcast wind %v, want (0.1, -0.05)", wind)
+	}
+
+	// Released at rest in the middle, far from every wall and paddle
+	ball := &room.Ball
+	*ball = Ball{X: float64(room.Config.Width) / 2, Y: float64(room.Config.Height) / 2}
+	startX, startY := ball.X, ball.Y
+	const ticks = 10
+	for range ticks {
+		if conceded := room.stepBall(ball); conceded != "" {
+			t.Fatalf("%s conceded in open court", conceded)
+		}
+	}
+	// The velocity grows by the wind each tick before moving the ball
+	drift := float64(ticks*(ticks+1)) / 2
+	if !near(ball.X, startX+0.1*drift) || !near(ball.Y, startY-0.05*drift) {
+		t.Fatalf("ball drifted to (%v, %v), want (%v, %v)", ball.X, ball.Y, startX+0.1*drift, startY-0.05*drift)
+	}
+}
+
+func TestCalmWindNotBroadcast(t *testing.T) {
+	room := newSteppedTestRoom(t)
+	room.Lock()
+	defer room.Unlock()
+	if wind := room.windVector(); wind != nil {
+		t.Fatalf("broadcast wind %v with none set", wind)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Deadline for writing one frame; a peer that can't take it in time is dropped
+const WriteWait = time.Second
+
+// Frames a connection may have waiting to be written. A peer that falls this
+// far behind is dropped rather than slowing the broadcast for everyone.
+const sendBuffer = 64
+
+var (
+	errSendBufferFull = errors.New("send buffer full")
+	errSenderClosed   = errors.New("connection closing")
+)
+
+// outFrame is a message waiting to be written
+type outFrame struct {
+	kind int // websocket.TextMessage, BinaryMessage or CloseMessage
+	data []byte
+}
+
+// sender owns the writing side of one connection. Frames are queued without
+// blocking and written, in order, by the sender's own goroutine, so a slow
+// peer only ever delays itself. It is the only writer of data frames on its
+// connection; control frames may still be written directly.
+type sender struct {
+	conn    *websocket.Conn
+	version string // Negotiated schema version; see protocol.go
+	frames  chan outFrame
+	done    chan struct{} // Closed when the writer goroutine exits
+
+	mu     sync.Mutex
+	closed bool  // No more frames are accepted
+	err    error // First write error, after which nothing more is written
+}
+
+// newSender starts the writer for a connection. The writer stops early,
+// dropping whatever is still queued, if ctx is done first.
+func newSender(ctx context.Context, conn *websocket.Conn) *sender {
+	s := &sender{
+		conn:    conn,
+		version: conn.Subprotocol(),
+		frames:  make(chan outFrame, sendBuffer),
+		done:    make(chan struct{}),
+	}
+	go s.run(ctx)
+	return s
+}
+
+// run writes queued frames until the sender is stopped or ctx is done. After
+// a failed write it closes the connection, which ends the handler's read
+// loop, and discards the rest.
+func (s *sender) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		var frame outFrame
+		select {
+		case f, ok := <-s.frames:
+			if !ok {
+				return
+			}
+			frame = f
+		case <-ctx.Done():
+			return
+		}
+
+		s.mu.Lock()
+		failed := s.err != nil
+		s.mu.Unlock()
+		if failed {
+			continue
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(WriteWait))
+		// Only matters if the connection negotiated compression
+		s.conn.EnableWriteCompression(len(frame.data) >= minCompressedFrame)
+		err := s.conn.WriteMessage(frame.kind, frame.data)
+		if err == nil && frame.kind == websocket.CloseMessage {
+			err = errSenderClosed
+		}
+		if err != nil {
+			// Reported by whoever sends next, or noticed by the read loop
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.conn.Close()
+		}
+	}
+}
+
+// send queues a frame without blocking. It fails if the buffer is full, the
+// sender is closing, or an earlier write failed.
+func (s *sender) send(kind int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.closed {
+		return errSenderClosed
+	}
+	select {
+	case s.frames <- outFrame{kind: kind, data: data}:
+		return nil
+	default:
+		return errSendBufferFull
+	}
+}
+
+// sendJSON queues a message as a JSON text frame in the connection's schema
+// version
+func (s *sender) sendJSON(msg Message) error {
+	data, err := encoderFor(s.version)(msg)
+	if err != nil {
+		return err
+	}
+	return s.send(websocket.TextMessage, data)
+}
+
+// closeWith queues a close frame after everything already queued, then stops
+// accepting frames. The connection is closed once the frame is written, or
+// right away if it can't be queued.
+func (s *sender) closeWith(code int, reason string) {
+	if err := s.send(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason)); err != nil {
+		s.conn.Close()
+	}
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+}
+
+// stop stops accepting frames and waits for the queued ones to be written or
+// discarded. The connection's handler calls it once, as it exits.
+func (s *sender) stop() {
+	s.mu.Lock()
+	s.closed = true
+	close(s.frames)
+	s.mu.Unlock()
+	<-s.done
+}
+
+// addSender registers a connection's writer with the room
+func (room *Room) addSender(conn *websocket.Conn, s *sender) {
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	room.senders[conn] = s
+}
+
+// removeSender forgets a connection's writer
+func (room *Room) removeSender(conn *websocket.Conn) {
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	delete(room.senders, conn)
+}
+
+// closeConn ends a connection in the room on purpose, with a close frame
+// once what is queued for it has been written
+func (room *Room) closeConn(conn *websocket.Conn, code int, reason string) {
+	room.clientsMutex.Lock()
+	defer room.clientsMutex.Unlock()
+	room.hangUp(conn, code, reason)
+}
+
+// hangUp is closeConn for callers already holding clientsMutex. A connection
+// without a writer yet is closed outright.
+func (room *Room) hangUp(conn *websocket.Conn, code int, reason string) {
+	if s, ok := room.senders[conn]; ok {
+		s.closeWith(code, reason)
+		return
+	}
+	conn.Close()
+}
+
+// sendTo queues a frame for a connection in the room. Caller must hold
+// clientsMutex.
+func (room *Room) sendTo(conn *websocket.Conn, kind int, data []byte) error {
+	s, ok := room.senders[conn]
+	if !ok {
+		return errSenderClosed
+	}
+	return s.send(kind, data)
+}
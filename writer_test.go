@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStalledReaderDoesNotBlockBroadcast(t *testing.T) {
+	s, ts := newTestServer(t, testConfig(t))
+	stalled := dialTest(t, ts, "")
+	readUntil(t, stalled, AssignMessage)
+	healthy := dialTest(t, ts, "")
+	readUntil(t, healthy, AssignMessage)
+
+	const floods = 300
+	var received atomic.Int32
+	go func() {
+		for {
+			msg, err := readMessage(healthy)
+			if err != nil {
+				return
+			}
+			if msg.Type == ChatMessage {
+				received.Add(1)
+			}
+		}
+	}()
+
+	// Far more than the stalled client's send buffer and socket can hold
+	room := s.lookupRoom(DefaultRoomID)
+	big := Message{Type: ChatMessage, Text: strings.Repeat("x", 64<<10)}
+	var slowest time.Duration
+	for range floods {
+		start := time.Now()
+		room.broadcast(big)
+		slowest = max(slowest, time.Since(start))
+		// Paced so a client that reads keeps up
+		time.Sleep(2 * time.Millisecond)
+	}
+	if slowest > WriteWait/4 {
+		t.Fatalf("a broadcast took %v with a stalled client connected", slowest)
+	}
+
+	waitFor(t, "the stalled client to be dropped", func() bool {
+		room.clientsMutex.Lock()
+		defer room.clientsMutex.Unlock()
+		return len(room.clients) == 1
+	})
+	waitFor(t, "the healthy client to get every message", func() bool { return received.Load() == floods })
+}